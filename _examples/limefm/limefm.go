@@ -23,6 +23,15 @@ var centerFrequency = flag.Float64("centerFrequency", 93.7e6, "Preset for Demodu
 var gain = flag.Float64("gain", 0.5, "Normalized Gain [0-1]")
 var antenna = flag.String("antenna", "LNAL", "Antenna Name [LNAL, LNAH, LNAW]")
 var channel = flag.Int("channel", 0, "Channel Number [0 => A, 1 => B]")
+var simulate = flag.Bool("simulate", false, "Use a simulated device with a FM station at centerFrequency")
+var device = flag.String("device", "", "Device selector, like serial=0009060B00471B22 (the first device if empty)")
+
+func check(err error) {
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
 
 func OnSamples(data []complex64, _ int, _ uint64) {
 	var demodDataI = demod.Work(data)
@@ -41,26 +50,18 @@ func main() {
 
 	demod = demodcore.MakeWBFMDemodulator(sampleRate, 120e3, uint32(*outputRate))
 
-	devices := limedrv.GetDevices()
+	var d *limedrv.LMSDevice
+	var err error
 
-	fmt.Fprintf(os.Stderr,"Found %d devices.\n", len(devices))
-
-	if len(devices) == 0 {
-		fmt.Fprintf(os.Stderr,"No devices found.\n")
-		os.Exit(1)
+	if *device != "" && !*simulate {
+		fmt.Fprintf(os.Stderr, "Opening device %s\n", *device)
+		d, err = limedrv.OpenBySelector(*device)
+	} else {
+		d, err = openFirstDevice()
 	}
+	check(err)
 
-	if len(devices) > 1 {
-		fmt.Fprintf(os.Stderr,"More than one device found. Selecting first one.\n")
-	}
-
-	var di = devices[0]
-
-	fmt.Fprintf(os.Stderr,"Opening device %s\n", di.DeviceName)
-
-	var d = limedrv.Open(di)
-
-	d.SetSampleRate(sampleRate, 8)
+	check(d.SetSampleRate(sampleRate, 8))
 
 	//log.Println(d.String())
 
@@ -68,14 +69,14 @@ func main() {
 
 	var ch = d.RXChannels[*channel]
 
-	ch.Enable().
-		SetAntennaByName(*antenna).
-		SetGainNormalized(*gain).
-		SetLPF(1.5e6).
-		EnableLPF().
-		SetDigitalLPF(300e3).
-		EnableDigitalLPF().
-		SetCenterFrequency(*centerFrequency)
+	check(ch.Enable())
+	check(ch.SetAntennaByName(*antenna))
+	check(ch.SetGainNormalized(*gain))
+	check(ch.SetLPF(1.5e6))
+	check(ch.EnableLPF())
+	check(ch.SetDigitalLPF(300e3))
+	check(ch.EnableDigitalLPF())
+	check(ch.SetCenterFrequency(*centerFrequency))
 
 	d.SetCallback(OnSamples)
 
@@ -89,15 +90,45 @@ func main() {
 		done <- true
 	}()
 
-
-	d.Start()
+	check(d.Start())
 
 	<-done
 
-	d.Stop()
+	check(d.Stop())
 
 	log.Println("Closing")
-	d.Close()
+	check(d.Close())
 
 	log.Println("Closed!")
-}
\ No newline at end of file
+}
+
+func openFirstDevice() (*limedrv.LMSDevice, error) {
+	devices := limedrv.GetDevices()
+
+	if *simulate {
+		sim := limedrv.NewSimBackend(
+			limedrv.SimSignal{Type: limedrv.SimFM, Frequency: *centerFrequency, Amplitude: 0.5, Deviation: 75e3, ModulationFrequency: 1e3},
+			limedrv.SimSignal{Type: limedrv.SimNoise, Amplitude: 0.01},
+		)
+		var err error
+		devices, err = limedrv.GetDevicesFrom(sim)
+		check(err)
+	}
+
+	fmt.Fprintf(os.Stderr, "Found %d devices.\n", len(devices))
+
+	if len(devices) == 0 {
+		fmt.Fprintf(os.Stderr, "No devices found.\n")
+		os.Exit(1)
+	}
+
+	if len(devices) > 1 {
+		fmt.Fprintf(os.Stderr, "More than one device found. Selecting first one.\n")
+	}
+
+	var di = devices[0]
+
+	fmt.Fprintf(os.Stderr, "Opening device %s\n", di.DeviceName)
+
+	return limedrv.Open(di)
+}
@@ -7,6 +7,11 @@ import (
 	"time"
 )
 
+func check(err error) {
+	if err != nil {
+		log.Fatalln(err)
+	}
+}
 
 func OnSamples(data []complex64, channel int, timestamp uint64) {
 	log.Println("Received samples from channel", channel, "with timestamp", timestamp)
@@ -30,7 +35,8 @@ func main() {
 
 	log.Printf("Opening device %s\n", di.DeviceName)
 
-	var d = limedrv.Open(di)
+	d, err := limedrv.Open(di)
+	check(err)
 	log.Println("Opened!")
 
 	log.Println(d.String())
@@ -42,23 +48,23 @@ func main() {
 
 	var ch = d.RXChannels[limedrv.ChannelA]
 
-	ch.Enable()
-	ch.SetAntennaByName("LNAW")
-	ch.SetGainNormalized(0.5)
-	ch.SetLPF(1e6)
-	ch.EnableLPF()
-	ch.SetCenterFrequency(106.3e6)
+	check(ch.Enable())
+	check(ch.SetAntennaByName("LNAW"))
+	check(ch.SetGainNormalized(0.5))
+	check(ch.SetLPF(1e6))
+	check(ch.EnableLPF())
+	check(ch.SetCenterFrequency(106.3e6))
 
 	d.SetCallback(OnSamples)
 
-	d.Start()
+	check(d.Start())
 
 	time.Sleep(500 * time.Millisecond)
 
-	d.Stop()
+	check(d.Stop())
 
 	log.Println("Closing")
-	d.Close()
+	check(d.Close())
 
 	log.Println("Closed!")
 }
\ No newline at end of file
@@ -0,0 +1,214 @@
+package limedrv
+
+// Range represents a range of values supported by the device, like LPF Bandwidth or Sample Rate.
+type Range struct {
+	Min  float64
+	Max  float64
+	Step float64
+}
+
+// StreamConfig is the configuration used by a Backend to setup a stream in a channel
+type StreamConfig struct {
+	// Channel is the channel number of the stream
+	Channel int
+	// IsRX is the direction of the stream
+	IsRX bool
+	// Format is the IQFormat of the samples in the stream
+	Format int
+	// FifoSize is the size of the stream FIFO in samples
+	FifoSize int
+	// ThroughputVsLatency is the balance between throughput (1) and latency (0)
+	ThroughputVsLatency float32
+}
+
+// StreamMeta is the metadata of a block of samples sent or received from a stream
+type StreamMeta struct {
+	// Timestamp is the hardware sample counter of the first sample in the block
+	Timestamp uint64
+	// WaitForTimestamp tells the hardware to wait for Timestamp before sending the block (TX Only)
+	WaitForTimestamp bool
+	// FlushPartialPacket tells the hardware to send the block even if it does not fill a packet (TX Only)
+	FlushPartialPacket bool
+}
+
+// StreamStatus is the status of a stream as reported by a Backend.
+// Underrun, Overrun and DroppedPackets count the events since the previous call to GetStreamStatus.
+type StreamStatus struct {
+	// Active tells if the stream is running
+	Active bool
+	// FifoFilledCount is the number of samples in the stream FIFO
+	FifoFilledCount int
+	// FifoSize is the size of the stream FIFO in samples
+	FifoSize int
+	// Underrun is the number of FIFO underruns
+	Underrun int
+	// Overrun is the number of FIFO overruns
+	Overrun int
+	// DroppedPackets is the number of packets dropped by the hardware
+	DroppedPackets int
+	// SampleRate is the measured sample rate of the stream in samples per second
+	SampleRate float64
+	// LinkRate is the measured data rate of the link with the device in bytes per second
+	LinkRate float64
+	// Timestamp is the current hardware sample counter
+	Timestamp uint64
+}
+
+// Backend is the interface between limedrv and the driver that talks to the hardware.
+// The default backend uses LimeSuite (through limewrap) and is used for all devices returned by GetDevices.
+// Alternative backends can be used through GetDevicesFrom, since Open always uses the backend that listed the device.
+//
+// All methods that receive a dev parameter receive the handle returned by Open.
+// All methods that receive a stream parameter receive the handle returned by SetupStream.
+// Errors returned should contain the message reported by the driver.
+type Backend interface {
+	// GetDeviceList returns all devices available in this backend
+	GetDeviceList() ([]DeviceInfo, error)
+	// Open opens the device and returns a handle to it
+	Open(device DeviceInfo) (dev uintptr, err error)
+	// Close closes the device
+	Close(dev uintptr) error
+	// Reset resets the device to its default state
+	Reset(dev uintptr) error
+	// Init initializes the device with its default configuration
+	Init(dev uintptr) error
+	// IsOpen returns true if the device is still connected
+	IsOpen(dev uintptr) bool
+	// GetDeviceInfo returns the information reported by the opened device
+	GetDeviceInfo(dev uintptr) (BoardInfo, error)
+	// GetLibraryVersion returns the version of the driver library
+	GetLibraryVersion() string
+	// SetLogHandler sets the function that receives the log messages of the driver.
+	// A nil handler restores the default driver logging.
+	SetLogHandler(handler func(level LogLevel, message string))
+
+	// GetNumChannels returns the number of channels in the specified direction
+	GetNumChannels(dev uintptr, isRX bool) (int, error)
+	// EnableChannel enables or disables a channel
+	EnableChannel(dev uintptr, isRX bool, channel int, enabled bool) error
+	// GetSampleRateRange returns the sample rate range supported by the device
+	GetSampleRateRange(dev uintptr, isRX bool) (Range, error)
+	// SetSampleRate sets the host sample rate for all channels, with oversample being the rf / host ratio
+	SetSampleRate(dev uintptr, sampleRate float64, oversample int) error
+	// GetSampleRate returns the host and rf sample rate of the channel
+	GetSampleRate(dev uintptr, isRX bool, channel int) (host float64, rf float64, err error)
+	// GetChipTemperature returns the LMS chip temperature in degrees celsius
+	GetChipTemperature(dev uintptr, index int) (float64, error)
+
+	// SetGaindB sets the channel gain in decibels
+	SetGaindB(dev uintptr, isRX bool, channel int, gain uint) error
+	// GetGaindB returns the channel gain in decibels
+	GetGaindB(dev uintptr, isRX bool, channel int) (uint, error)
+	// SetNormalizedGain sets the channel normalized gain [0-1]
+	SetNormalizedGain(dev uintptr, isRX bool, channel int, gain float64) error
+	// GetNormalizedGain returns the channel normalized gain [0-1]
+	GetNormalizedGain(dev uintptr, isRX bool, channel int) (float64, error)
+
+	// GetLPFBWRange returns the analog low pass filter bandwidth range
+	GetLPFBWRange(dev uintptr, isRX bool) (Range, error)
+	// SetLPFBW sets the analog low pass filter bandwidth of the channel
+	SetLPFBW(dev uintptr, isRX bool, channel int, bandwidth float64) error
+	// GetLPFBW returns the analog low pass filter bandwidth of the channel
+	GetLPFBW(dev uintptr, isRX bool, channel int) (float64, error)
+	// SetLPF enables or disables the analog low pass filter of the channel
+	SetLPF(dev uintptr, isRX bool, channel int, enabled bool) error
+
+	// SetGFIRLPF configures the digital (GFIR) filter of the channel as a low pass filter
+	SetGFIRLPF(dev uintptr, isRX bool, channel int, enabled bool, bandwidth float64) error
+	// SetGFIRCoeff sets the taps of the specified GFIR of the channel
+	SetGFIRCoeff(dev uintptr, isRX bool, channel int, gfir int, taps []float64) error
+	// SetGFIR enables or disables the specified GFIR of the channel
+	SetGFIR(dev uintptr, isRX bool, channel int, gfir int, enabled bool) error
+
+	// SetLOFrequency sets the LO frequency of the channel in Hertz
+	SetLOFrequency(dev uintptr, isRX bool, channel int, frequency float64) error
+	// GetLOFrequency returns the LO frequency of the channel in Hertz
+	GetLOFrequency(dev uintptr, isRX bool, channel int) (float64, error)
+
+	// SetNCOFrequency loads the NCO frequency table of the channel with NCOValueCount frequencies in Hertz and a phase offset in degrees
+	SetNCOFrequency(dev uintptr, isRX bool, channel int, frequencies []float64, phaseOffset float64) error
+	// GetNCOFrequency returns the NCO frequency table of the channel and its phase offset in degrees
+	GetNCOFrequency(dev uintptr, isRX bool, channel int) (frequencies []float64, phaseOffset float64, err error)
+	// SetNCOPhase loads the NCO phase table of the channel with NCOValueCount phases in degrees and a frequency in Hertz
+	SetNCOPhase(dev uintptr, isRX bool, channel int, phases []float64, frequency float64) error
+	// GetNCOPhase returns the NCO phase table of the channel in degrees and its frequency in Hertz
+	GetNCOPhase(dev uintptr, isRX bool, channel int) (phases []float64, frequency float64, err error)
+	// SetNCOIndex selects the active entry of the NCO table of the channel, or disables the NCO if index is -1
+	SetNCOIndex(dev uintptr, isRX bool, channel int, index int, downConvert bool) error
+	// GetNCOIndex returns the active entry of the NCO table of the channel
+	GetNCOIndex(dev uintptr, isRX bool, channel int) (int, error)
+
+	// GetAntennaList returns the name of the antenna ports of the channel
+	GetAntennaList(dev uintptr, isRX bool, channel int) ([]string, error)
+	// GetAntennaBW returns the frequency range of the antenna port
+	GetAntennaBW(dev uintptr, isRX bool, channel int, antenna int) (Range, error)
+	// SetAntenna selects the antenna port of the channel
+	SetAntenna(dev uintptr, isRX bool, channel int, antenna int) error
+
+	// SetTestSignal replaces the samples of the channel by a test signal. dcI and dcQ are only used by TestSignalDC.
+	SetTestSignal(dev uintptr, isRX bool, channel int, signal TestSignal, dcI, dcQ int16) error
+	// GetTestSignal returns the test signal set in the channel
+	GetTestSignal(dev uintptr, isRX bool, channel int) (TestSignal, error)
+
+	// Calibrate runs the automatic calibration (DC offset and IQ imbalance) of the channel for the specified bandwidth in Hertz
+	Calibrate(dev uintptr, isRX bool, channel int, bandwidth float64) error
+	// EnableCalibCache enables or disables the cache of calibration results of the device
+	EnableCalibCache(dev uintptr, enabled bool) error
+
+	// VCTCXORead returns the trim value of the reference oscillator DAC
+	VCTCXORead(dev uintptr) (uint16, error)
+	// VCTCXOWrite sets the trim value of the reference oscillator DAC
+	VCTCXOWrite(dev uintptr, value uint16) error
+	// GetClockFreq returns the frequency of a clock of the device in Hertz
+	GetClockFreq(dev uintptr, clock Clock) (float64, error)
+	// SetClockFreq sets the frequency of a clock of the device in Hertz. A external reference of 0 selects the internal reference.
+	SetClockFreq(dev uintptr, clock Clock, frequency float64) error
+
+	// ReadLMSReg returns the value of a register of the LMS7 chip
+	ReadLMSReg(dev uintptr, address uint16) (uint16, error)
+	// WriteLMSReg sets the value of a register of the LMS7 chip
+	WriteLMSReg(dev uintptr, address uint16, value uint16) error
+	// ReadFPGAReg returns the value of a register of the board FPGA
+	ReadFPGAReg(dev uintptr, address uint16) (uint16, error)
+	// WriteFPGAReg sets the value of a register of the board FPGA
+	WriteFPGAReg(dev uintptr, address uint16, value uint16) error
+	// Synchronize writes the registers cached by the backend to the chip if toChip is true, or reads them from the chip otherwise
+	Synchronize(dev uintptr, toChip bool) error
+
+	// GetProgramModes returns the names of the program modes supported by the device
+	GetProgramModes(dev uintptr) ([]string, error)
+	// Program writes a firmware or gateware image to the device using the program mode.
+	// progress is called with the bytes written and the total bytes, and aborts the programming by returning false.
+	Program(dev uintptr, mode string, image []byte, progress func(done, total int) bool) error
+
+	// UploadWFM uploads a waveform to the FPGA memory, with one buffer of sampleCount samples in the IQFormat for each TX channel
+	UploadWFM(dev uintptr, buffers [][]byte, sampleCount int, format int) error
+	// EnableTxWFM starts or stops the playback of the uploaded waveform in a TX channel
+	EnableTxWFM(dev uintptr, channel int, enabled bool) error
+
+	// GPIORead returns the levels of the GPIO banks 0 to count - 1, with 8 pins in each bank
+	GPIORead(dev uintptr, count int) ([]byte, error)
+	// GPIOWrite sets the levels of the GPIO banks 0 to len(banks) - 1
+	GPIOWrite(dev uintptr, banks []byte) error
+	// GPIODirRead returns the directions of the GPIO banks 0 to count - 1, with set bits being outputs
+	GPIODirRead(dev uintptr, count int) ([]byte, error)
+	// GPIODirWrite sets the directions of the GPIO banks 0 to len(banks) - 1, with set bits being outputs
+	GPIODirWrite(dev uintptr, banks []byte) error
+
+	// SetupStream creates a stream and returns a handle to it
+	SetupStream(dev uintptr, config StreamConfig) (stream uintptr, err error)
+	// DestroyStream destroys a stream created by SetupStream
+	DestroyStream(dev uintptr, stream uintptr) error
+	// StartStream starts a stream
+	StartStream(stream uintptr) error
+	// StopStream stops a stream
+	StopStream(stream uintptr) error
+	// RecvStream reads up to sampleCount samples into buffer and returns the number of samples read.
+	// The buffer holds interleaved IQ samples in the stream format. meta.Timestamp is filled with the first sample timestamp.
+	RecvStream(stream uintptr, buffer []byte, sampleCount int, meta *StreamMeta, timeoutMs uint) (int, error)
+	// SendStream writes sampleCount samples from buffer and returns the number of samples sent.
+	// The buffer holds interleaved IQ samples in the stream format.
+	SendStream(stream uintptr, buffer []byte, sampleCount int, meta *StreamMeta, timeoutMs uint) (int, error)
+	// GetStreamStatus returns the stream status. Event counters are reset at each call.
+	GetStreamStatus(stream uintptr) (StreamStatus, error)
+}
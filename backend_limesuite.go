@@ -0,0 +1,739 @@
+//go:build !nolimesuite
+// +build !nolimesuite
+
+package limedrv
+
+import (
+	"fmt"
+	"github.com/racerxdl/limedrv/limewrap"
+	"sync"
+	"unsafe"
+)
+
+// defaultBackend is the backend used by GetDevices. It talks to the hardware through LimeSuite.
+var defaultBackend Backend = limeSuiteBackend{}
+
+// suiteError is a error message reported by LimeSuite
+type suiteError string
+
+func (e suiteError) Error() string {
+	return string(e)
+}
+
+// Is allows LimeSuite messages to be matched against limedrv sentinel errors
+func (e suiteError) Is(target error) bool {
+	return target != nil && classifyMessage(string(e)) == target
+}
+
+func lastSuiteError() error {
+	return suiteError(limewrap.LMS_GetLastErrorMessage())
+}
+
+func createLms_range_t() limewrap.Lms_range_t {
+	return limewrap.NewLms_range_t()
+}
+
+func createLms_stream_t() limewrap.Lms_stream_t {
+	return limewrap.NewLms_stream_t()
+}
+
+func createLms_stream_meta_t() limewrap.Lms_stream_meta_t {
+	return limewrap.NewLms_stream_meta_t()
+}
+
+// suiteStreamMetas has the lms_stream_meta_t of each stream. It is allocated in SetupStream
+// and reused by every RecvStream and SendStream call, so streaming does not allocate C memory.
+var (
+	suiteStreamMetasMtx sync.RWMutex
+	suiteStreamMetas    = make(map[uintptr]limewrap.Lms_stream_meta_t)
+)
+
+// suiteStreamMeta returns the lms_stream_meta_t of the stream with the fields of meta
+func suiteStreamMeta(stream uintptr, meta *StreamMeta) (limewrap.Lms_stream_meta_t, error) {
+	suiteStreamMetasMtx.RLock()
+	m, ok := suiteStreamMetas[stream]
+	suiteStreamMetasMtx.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("stream %d is not set up: %w", stream, ErrStreamFailure)
+	}
+
+	m.SetTimestamp(meta.Timestamp)
+	m.SetWaitForTimestamp(meta.WaitForTimestamp)
+	m.SetFlushPartialPacket(meta.FlushPartialPacket)
+	return m, nil
+}
+
+// suiteTestSignals maps the limedrv test signals to the LimeSuite ones
+var suiteTestSignals = map[TestSignal]int{
+	TestSignalNone:        limewrap.LMS_TESTSIG_NONE,
+	TestSignalNCODiv8:     limewrap.LMS_TESTSIG_NCODIV8,
+	TestSignalNCODiv4:     limewrap.LMS_TESTSIG_NCODIV4,
+	TestSignalNCODiv8Full: limewrap.LMS_TESTSIG_NCODIV8F,
+	TestSignalNCODiv4Full: limewrap.LMS_TESTSIG_NCODIV4F,
+	TestSignalDC:          limewrap.LMS_TESTSIG_DC,
+}
+
+// suiteClocks maps the limedrv clocks to the LimeSuite ones
+var suiteClocks = map[Clock]int{
+	ClockReference:         limewrap.LMS_CLOCK_REF,
+	ClockSXR:               limewrap.LMS_CLOCK_SXR,
+	ClockSXT:               limewrap.LMS_CLOCK_SXT,
+	ClockCGEN:              limewrap.LMS_CLOCK_CGEN,
+	ClockRXTSP:             limewrap.LMS_CLOCK_RXTSP,
+	ClockTXTSP:             limewrap.LMS_CLOCK_TXTSP,
+	ClockExternalReference: limewrap.LMS_CLOCK_EXTREF,
+}
+
+func createLms_stream_status_t() limewrap.Lms_stream_status_t {
+	return limewrap.NewLms_stream_status_t()
+}
+
+// suiteFormat converts a limedrv IQFormat to the LimeSuite stream format
+func suiteFormat(format int) int {
+	switch format {
+	case FormatFloat32:
+		return limewrap.Lms_stream_tLMS_FMT_F32
+	case FormatInt12:
+		return limewrap.Lms_stream_tLMS_FMT_I12
+	default:
+		return limewrap.Lms_stream_tLMS_FMT_I16
+	}
+}
+
+// suiteWFMFormat converts a limedrv IQFormat to the LMS_UploadWFM format
+func suiteWFMFormat(format int) int {
+	switch format {
+	case FormatInt12:
+		return 0
+	case FormatInt16:
+		return 1
+	default:
+		return 2
+	}
+}
+
+// suiteLogLevel converts a LimeSuite log level to a limedrv LogLevel.
+// Critical and unknown levels are reported as errors, so they are not hidden by a Logger that filters debug messages.
+func suiteLogLevel(level int) LogLevel {
+	switch level {
+	case limewrap.LMS_LOG_CRITICAL, limewrap.LMS_LOG_ERROR:
+		return LogError
+	case limewrap.LMS_LOG_WARNING:
+		return LogWarning
+	case limewrap.LMS_LOG_INFO:
+		return LogInfo
+	case limewrap.LMS_LOG_DEBUG:
+		return LogDebug
+	default:
+		return LogError
+	}
+}
+
+// limeSuiteBackend is the Backend implementation that calls LimeSuite through limewrap
+type limeSuiteBackend struct{}
+
+func (limeSuiteBackend) GetDeviceList() ([]DeviceInfo, error) {
+	devCount := limewrap.LMS_GetDeviceList(nil)
+	if devCount < 0 {
+		return nil, lastSuiteError()
+	}
+
+	ret := make([]DeviceInfo, 0, devCount)
+
+	if devCount > 0 {
+		var z [maxListedDevices][lmsInfoStrLength]byte
+		t := (*string)(unsafe.Pointer(&z))
+		devCount = limewrap.LMS_GetDeviceList(t)
+		if devCount < 0 {
+			return nil, lastSuiteError()
+		}
+		if devCount > maxListedDevices {
+			devCount = maxListedDevices
+		}
+		for i := 0; i < devCount; i++ {
+			ret = append(ret, entry2dev(z[i][:]))
+		}
+	}
+
+	return ret, nil
+}
+
+func (limeSuiteBackend) Open(device DeviceInfo) (uintptr, error) {
+	ptr := uintptr(0)
+	if limewrap.LMS_Open(&ptr, device.origDevString, 0) != 0 {
+		return 0, lastSuiteError()
+	}
+	return ptr, nil
+}
+
+func (limeSuiteBackend) Close(dev uintptr) error {
+	if limewrap.LMS_Close(dev) != 0 {
+		return lastSuiteError()
+	}
+	return nil
+}
+
+func (limeSuiteBackend) IsOpen(dev uintptr) bool {
+	return limewrap.LMS_IsOpen(dev, 0)
+}
+
+func (limeSuiteBackend) GetDeviceInfo(dev uintptr) (BoardInfo, error) {
+	info := limewrap.LMS_GetDeviceInfo(dev)
+	if info == nil || info.Swigcptr() == 0 {
+		return BoardInfo{}, lastSuiteError()
+	}
+
+	// The struct is owned by LimeSuite, so it is not deleted
+	return BoardInfo{
+		DeviceName:          cleanString(info.GetDeviceName()),
+		ExpansionName:       cleanString(info.GetExpansionName()),
+		FirmwareVersion:     cleanString(info.GetFirmwareVersion()),
+		HardwareVersion:     cleanString(info.GetHardwareVersion()),
+		ProtocolVersion:     cleanString(info.GetProtocolVersion()),
+		BoardSerialNumber:   info.GetBoardSerialNumber(),
+		GatewareVersion:     cleanString(info.GetGatewareVersion()),
+		GatewareTargetBoard: cleanString(info.GetGatewareTargetBoard()),
+	}, nil
+}
+
+func (limeSuiteBackend) GetLibraryVersion() string {
+	return cleanString(limewrap.LMS_GetLibraryVersion())
+}
+
+func (limeSuiteBackend) SetLogHandler(handler func(level LogLevel, message string)) {
+	if handler == nil {
+		limewrap.LMS_RegisterGoLogHandler(nil)
+		return
+	}
+	limewrap.LMS_RegisterGoLogHandler(func(level int, message string) {
+		handler(suiteLogLevel(level), message)
+	})
+}
+
+func (limeSuiteBackend) Reset(dev uintptr) error {
+	if limewrap.LMS_Reset(dev) != 0 {
+		return lastSuiteError()
+	}
+	return nil
+}
+
+func (limeSuiteBackend) Init(dev uintptr) error {
+	if limewrap.LMS_Init(dev) != 0 {
+		return lastSuiteError()
+	}
+	return nil
+}
+
+func (limeSuiteBackend) GetNumChannels(dev uintptr, isRX bool) (int, error) {
+	n := limewrap.LMS_GetNumChannels(dev, !isRX)
+	if n < 0 {
+		return 0, lastSuiteError()
+	}
+	return n, nil
+}
+
+func (limeSuiteBackend) EnableChannel(dev uintptr, isRX bool, channel int, enabled bool) error {
+	if limewrap.LMS_EnableChannel(dev, !isRX, int64(channel), enabled) != 0 {
+		return lastSuiteError()
+	}
+	return nil
+}
+
+func (limeSuiteBackend) GetSampleRateRange(dev uintptr, isRX bool) (Range, error) {
+	var r = createLms_range_t()
+	defer limewrap.DeleteLms_range_t(r)
+	if limewrap.LMS_GetSampleRateRange(dev, !isRX, r) != 0 {
+		return Range{}, lastSuiteError()
+	}
+	return Range{Min: r.GetMin(), Max: r.GetMax(), Step: r.GetStep()}, nil
+}
+
+func (limeSuiteBackend) SetSampleRate(dev uintptr, sampleRate float64, oversample int) error {
+	if limewrap.LMS_SetSampleRate(dev, sampleRate, int64(oversample)) != 0 {
+		return lastSuiteError()
+	}
+	return nil
+}
+
+func (limeSuiteBackend) GetSampleRate(dev uintptr, isRX bool, channel int) (host float64, rf float64, err error) {
+	if limewrap.LMS_GetSampleRate(dev, !isRX, int64(channel), &host, &rf) != 0 {
+		return 0, 0, lastSuiteError()
+	}
+	return host, rf, nil
+}
+
+func (limeSuiteBackend) GetChipTemperature(dev uintptr, index int) (temp float64, err error) {
+	if limewrap.LMS_GetChipTemperature(dev, int64(index), &temp) != 0 {
+		return 0, lastSuiteError()
+	}
+	return temp, nil
+}
+
+func (limeSuiteBackend) SetGaindB(dev uintptr, isRX bool, channel int, gain uint) error {
+	if limewrap.LMS_SetGaindB(dev, !isRX, int64(channel), gain) != 0 {
+		return lastSuiteError()
+	}
+	return nil
+}
+
+func (limeSuiteBackend) GetGaindB(dev uintptr, isRX bool, channel int) (gain uint, err error) {
+	if limewrap.LMS_GetGaindB(dev, !isRX, int64(channel), &gain) != 0 {
+		return 0, lastSuiteError()
+	}
+	return gain, nil
+}
+
+func (limeSuiteBackend) SetNormalizedGain(dev uintptr, isRX bool, channel int, gain float64) error {
+	if limewrap.LMS_SetNormalizedGain(dev, !isRX, int64(channel), gain) != 0 {
+		return lastSuiteError()
+	}
+	return nil
+}
+
+func (limeSuiteBackend) GetNormalizedGain(dev uintptr, isRX bool, channel int) (gain float64, err error) {
+	if limewrap.LMS_GetNormalizedGain(dev, !isRX, int64(channel), &gain) != 0 {
+		return 0, lastSuiteError()
+	}
+	return gain, nil
+}
+
+func (limeSuiteBackend) GetLPFBWRange(dev uintptr, isRX bool) (Range, error) {
+	var r = createLms_range_t()
+	defer limewrap.DeleteLms_range_t(r)
+	if limewrap.LMS_GetLPFBWRange(dev, !isRX, r) != 0 {
+		return Range{}, lastSuiteError()
+	}
+	return Range{Min: r.GetMin(), Max: r.GetMax(), Step: r.GetStep()}, nil
+}
+
+func (limeSuiteBackend) SetLPFBW(dev uintptr, isRX bool, channel int, bandwidth float64) error {
+	if limewrap.LMS_SetLPFBW(dev, !isRX, int64(channel), bandwidth) != 0 {
+		return lastSuiteError()
+	}
+	return nil
+}
+
+func (limeSuiteBackend) GetLPFBW(dev uintptr, isRX bool, channel int) (bandwidth float64, err error) {
+	if limewrap.LMS_GetLPFBW(dev, !isRX, int64(channel), &bandwidth) != 0 {
+		return 0, lastSuiteError()
+	}
+	return bandwidth, nil
+}
+
+func (limeSuiteBackend) SetLPF(dev uintptr, isRX bool, channel int, enabled bool) error {
+	if limewrap.LMS_SetLPF(dev, !isRX, int64(channel), enabled) != 0 {
+		return lastSuiteError()
+	}
+	return nil
+}
+
+func (limeSuiteBackend) SetGFIRLPF(dev uintptr, isRX bool, channel int, enabled bool, bandwidth float64) error {
+	if limewrap.LMS_SetGFIRLPF(dev, !isRX, int64(channel), enabled, bandwidth) != 0 {
+		return lastSuiteError()
+	}
+	return nil
+}
+
+func (limeSuiteBackend) SetGFIRCoeff(dev uintptr, isRX bool, channel int, gfir int, taps []float64) error {
+	if limewrap.LMS_SetGFIRCoeff(dev, !isRX, int64(channel), limewrap.Lms_gfir_t(gfir), &taps[0], int64(len(taps))) != 0 {
+		return lastSuiteError()
+	}
+	return nil
+}
+
+func (limeSuiteBackend) SetGFIR(dev uintptr, isRX bool, channel int, gfir int, enabled bool) error {
+	if limewrap.LMS_SetGFIR(dev, !isRX, int64(channel), limewrap.Lms_gfir_t(gfir), enabled) != 0 {
+		return lastSuiteError()
+	}
+	return nil
+}
+
+func (limeSuiteBackend) SetNCOFrequency(dev uintptr, isRX bool, channel int, frequencies []float64, phaseOffset float64) error {
+	var table [NCOValueCount]float64
+	copy(table[:], frequencies)
+	if limewrap.LMS_SetNCOFrequency(dev, !isRX, int64(channel), &table[0], phaseOffset) != 0 {
+		return lastSuiteError()
+	}
+	return nil
+}
+
+func (limeSuiteBackend) GetNCOFrequency(dev uintptr, isRX bool, channel int) ([]float64, float64, error) {
+	var table [NCOValueCount]float64
+	var phaseOffset float64
+	if limewrap.LMS_GetNCOFrequency(dev, !isRX, int64(channel), &table[0], &phaseOffset) != 0 {
+		return nil, 0, lastSuiteError()
+	}
+	return table[:], phaseOffset, nil
+}
+
+func (limeSuiteBackend) SetNCOPhase(dev uintptr, isRX bool, channel int, phases []float64, frequency float64) error {
+	var table [NCOValueCount]float64
+	copy(table[:], phases)
+	if limewrap.LMS_SetNCOPhase(dev, !isRX, int64(channel), &table[0], frequency) != 0 {
+		return lastSuiteError()
+	}
+	return nil
+}
+
+func (limeSuiteBackend) GetNCOPhase(dev uintptr, isRX bool, channel int) ([]float64, float64, error) {
+	var table [NCOValueCount]float64
+	var frequency float64
+	if limewrap.LMS_GetNCOPhase(dev, !isRX, int64(channel), &table[0], &frequency) != 0 {
+		return nil, 0, lastSuiteError()
+	}
+	return table[:], frequency, nil
+}
+
+func (limeSuiteBackend) SetNCOIndex(dev uintptr, isRX bool, channel int, index int, downConvert bool) error {
+	if limewrap.LMS_SetNCOIndex(dev, !isRX, int64(channel), index, downConvert) != 0 {
+		return lastSuiteError()
+	}
+	return nil
+}
+
+func (limeSuiteBackend) GetNCOIndex(dev uintptr, isRX bool, channel int) (int, error) {
+	var index = limewrap.LMS_GetNCOIndex(dev, !isRX, int64(channel))
+	if index < 0 {
+		return 0, lastSuiteError()
+	}
+	return index, nil
+}
+
+func (limeSuiteBackend) SetTestSignal(dev uintptr, isRX bool, channel int, signal TestSignal, dcI, dcQ int16) error {
+	if limewrap.LMS_SetTestSignal(dev, !isRX, int64(channel), limewrap.Lms_testsig_t(suiteTestSignals[signal]), dcI, dcQ) != 0 {
+		return lastSuiteError()
+	}
+	return nil
+}
+
+func (limeSuiteBackend) GetTestSignal(dev uintptr, isRX bool, channel int) (TestSignal, error) {
+	var sig limewrap.Lms_testsig_t
+	if limewrap.LMS_GetTestSignal(dev, !isRX, int64(channel), &sig) != 0 {
+		return TestSignalNone, lastSuiteError()
+	}
+
+	for signal, suiteSignal := range suiteTestSignals {
+		if int(sig) == suiteSignal {
+			return signal, nil
+		}
+	}
+	return TestSignalNone, fmt.Errorf("unknown test signal %d", int(sig))
+}
+
+func (limeSuiteBackend) GPIORead(dev uintptr, count int) ([]byte, error) {
+	var banks = make([]byte, count)
+	if limewrap.LMS_GPIORead(dev, &banks[0], int64(count)) != 0 {
+		return nil, lastSuiteError()
+	}
+	return banks, nil
+}
+
+func (limeSuiteBackend) GPIOWrite(dev uintptr, banks []byte) error {
+	if limewrap.LMS_GPIOWrite(dev, &banks[0], int64(len(banks))) != 0 {
+		return lastSuiteError()
+	}
+	return nil
+}
+
+func (limeSuiteBackend) GPIODirRead(dev uintptr, count int) ([]byte, error) {
+	var banks = make([]byte, count)
+	if limewrap.LMS_GPIODirRead(dev, &banks[0], int64(count)) != 0 {
+		return nil, lastSuiteError()
+	}
+	return banks, nil
+}
+
+func (limeSuiteBackend) GPIODirWrite(dev uintptr, banks []byte) error {
+	if limewrap.LMS_GPIODirWrite(dev, &banks[0], int64(len(banks))) != 0 {
+		return lastSuiteError()
+	}
+	return nil
+}
+
+func (limeSuiteBackend) Calibrate(dev uintptr, isRX bool, channel int, bandwidth float64) error {
+	if limewrap.LMS_Calibrate(dev, !isRX, int64(channel), bandwidth, 0) != 0 {
+		return lastSuiteError()
+	}
+	return nil
+}
+
+func (limeSuiteBackend) EnableCalibCache(dev uintptr, enabled bool) error {
+	if limewrap.LMS_EnableCalibCache(dev, enabled) != 0 {
+		return lastSuiteError()
+	}
+	return nil
+}
+
+func (limeSuiteBackend) VCTCXORead(dev uintptr) (uint16, error) {
+	var value uint16
+	if limewrap.LMS_VCTCXORead(dev, &value) != 0 {
+		return 0, lastSuiteError()
+	}
+	return value, nil
+}
+
+func (limeSuiteBackend) VCTCXOWrite(dev uintptr, value uint16) error {
+	if limewrap.LMS_VCTCXOWrite(dev, value) != 0 {
+		return lastSuiteError()
+	}
+	return nil
+}
+
+func (limeSuiteBackend) GetClockFreq(dev uintptr, clock Clock) (float64, error) {
+	var frequency float64
+	if limewrap.LMS_GetClockFreq(dev, int64(suiteClocks[clock]), &frequency) != 0 {
+		return 0, lastSuiteError()
+	}
+	return frequency, nil
+}
+
+func (limeSuiteBackend) SetClockFreq(dev uintptr, clock Clock, frequency float64) error {
+	if limewrap.LMS_SetClockFreq(dev, int64(suiteClocks[clock]), frequency) != 0 {
+		return lastSuiteError()
+	}
+	return nil
+}
+
+func (limeSuiteBackend) ReadLMSReg(dev uintptr, address uint16) (uint16, error) {
+	var value uint16
+	if limewrap.LMS_ReadLMSReg(dev, uint(address), &value) != 0 {
+		return 0, lastSuiteError()
+	}
+	return value, nil
+}
+
+func (limeSuiteBackend) WriteLMSReg(dev uintptr, address uint16, value uint16) error {
+	if limewrap.LMS_WriteLMSReg(dev, uint(address), value) != 0 {
+		return lastSuiteError()
+	}
+	return nil
+}
+
+func (limeSuiteBackend) ReadFPGAReg(dev uintptr, address uint16) (uint16, error) {
+	var value uint16
+	if limewrap.LMS_ReadFPGAReg(dev, uint(address), &value) != 0 {
+		return 0, lastSuiteError()
+	}
+	return value, nil
+}
+
+func (limeSuiteBackend) WriteFPGAReg(dev uintptr, address uint16, value uint16) error {
+	if limewrap.LMS_WriteFPGAReg(dev, uint(address), value) != 0 {
+		return lastSuiteError()
+	}
+	return nil
+}
+
+func (limeSuiteBackend) Synchronize(dev uintptr, toChip bool) error {
+	if limewrap.LMS_Synchronize(dev, toChip) != 0 {
+		return lastSuiteError()
+	}
+	return nil
+}
+
+func (limeSuiteBackend) GetProgramModes(dev uintptr) ([]string, error) {
+	count := limewrap.LMS_GetProgramModes(dev, nil)
+	if count < 0 {
+		return nil, lastSuiteError()
+	}
+
+	modes := make([]string, count)
+
+	if count > 0 {
+		var nameArr = make([]byte, 16*count) // 16 bytes per lms_name_t
+		var namePtr = (*string)(unsafe.Pointer(&nameArr[0]))
+		if limewrap.LMS_GetProgramModes(dev, namePtr) < 0 {
+			return nil, lastSuiteError()
+		}
+		for i := 0; i < count; i++ {
+			modes[i] = cleanString(string(nameArr[i*16 : (i+1)*16]))
+		}
+	}
+
+	return modes, nil
+}
+
+func (limeSuiteBackend) Program(dev uintptr, mode string, image []byte, progress func(done, total int) bool) error {
+	var aborted = false
+	var callback limewrap.ProgramProgress
+	if progress != nil {
+		callback = func(sent, total int, message string) bool {
+			aborted = !progress(sent, total)
+			return aborted
+		}
+	}
+
+	if limewrap.LMS_ProgramWithProgress(dev, image, mode, callback) != 0 {
+		if aborted {
+			return ErrAborted
+		}
+		return lastSuiteError()
+	}
+	return nil
+}
+
+func (limeSuiteBackend) UploadWFM(dev uintptr, buffers [][]byte, sampleCount int, format int) error {
+	if limewrap.LMS_UploadWFMBuffers(dev, buffers, sampleCount, suiteWFMFormat(format)) != 0 {
+		return lastSuiteError()
+	}
+	return nil
+}
+
+func (limeSuiteBackend) EnableTxWFM(dev uintptr, channel int, enabled bool) error {
+	if limewrap.LMS_EnableTxWFM(dev, uint(channel), enabled) != 0 {
+		return lastSuiteError()
+	}
+	return nil
+}
+
+func (limeSuiteBackend) SetLOFrequency(dev uintptr, isRX bool, channel int, frequency float64) error {
+	if limewrap.LMS_SetLOFrequency(dev, !isRX, int64(channel), frequency) != 0 {
+		return lastSuiteError()
+	}
+	return nil
+}
+
+func (limeSuiteBackend) GetLOFrequency(dev uintptr, isRX bool, channel int) (frequency float64, err error) {
+	if limewrap.LMS_GetLOFrequency(dev, !isRX, int64(channel), &frequency) != 0 {
+		return 0, lastSuiteError()
+	}
+	return frequency, nil
+}
+
+func (limeSuiteBackend) GetAntennaList(dev uintptr, isRX bool, channel int) ([]string, error) {
+	antennas := limewrap.LMS_GetAntennaList(dev, !isRX, int64(channel), nil)
+	if antennas < 0 {
+		return nil, lastSuiteError()
+	}
+
+	names := make([]string, antennas)
+
+	if antennas > 0 {
+		var nameArr = make([]byte, 16*antennas) // 16 bytes per lms_name_t
+		var namePtr = (*string)(unsafe.Pointer(&nameArr[0]))
+		if limewrap.LMS_GetAntennaList(dev, !isRX, int64(channel), namePtr) < 0 {
+			return nil, lastSuiteError()
+		}
+		for a := 0; a < antennas; a++ {
+			names[a] = cleanString(string(nameArr[a*16 : (a+1)*16]))
+		}
+	}
+
+	return names, nil
+}
+
+func (limeSuiteBackend) GetAntennaBW(dev uintptr, isRX bool, channel int, antenna int) (Range, error) {
+	var r = createLms_range_t()
+	defer limewrap.DeleteLms_range_t(r)
+	if limewrap.LMS_GetAntennaBW(dev, !isRX, int64(channel), int64(antenna), r) != 0 {
+		return Range{}, lastSuiteError()
+	}
+	return Range{Min: r.GetMin(), Max: r.GetMax(), Step: r.GetStep()}, nil
+}
+
+func (limeSuiteBackend) SetAntenna(dev uintptr, isRX bool, channel int, antenna int) error {
+	if limewrap.LMS_SetAntenna(dev, !isRX, int64(channel), int64(antenna)) != 0 {
+		return lastSuiteError()
+	}
+	return nil
+}
+
+func (limeSuiteBackend) SetupStream(dev uintptr, config StreamConfig) (uintptr, error) {
+	var s = createLms_stream_t()
+	s.SetChannel(uint(config.Channel))
+	s.SetDataFmt(suiteFormat(config.Format))
+	s.SetFifoSize(uint(config.FifoSize))
+	s.SetIsTx(!config.IsRX)
+	s.SetThroughputVsLatency(config.ThroughputVsLatency)
+
+	if limewrap.LMS_SetupStream(dev, s) != 0 {
+		limewrap.DeleteLms_stream_t(s)
+		return 0, lastSuiteError()
+	}
+
+	suiteStreamMetasMtx.Lock()
+	suiteStreamMetas[s.Swigcptr()] = createLms_stream_meta_t()
+	suiteStreamMetasMtx.Unlock()
+
+	return s.Swigcptr(), nil
+}
+
+func (limeSuiteBackend) DestroyStream(dev uintptr, stream uintptr) error {
+	var s = limewrap.SwigcptrLms_stream_t(stream)
+	defer limewrap.DeleteLms_stream_t(s)
+
+	suiteStreamMetasMtx.Lock()
+	if m, ok := suiteStreamMetas[stream]; ok {
+		limewrap.DeleteLms_stream_meta_t(m)
+		delete(suiteStreamMetas, stream)
+	}
+	suiteStreamMetasMtx.Unlock()
+	if limewrap.LMS_DestroyStream(dev, s) != 0 {
+		return lastSuiteError()
+	}
+	return nil
+}
+
+func (limeSuiteBackend) StartStream(stream uintptr) error {
+	if limewrap.LMS_StartStream(limewrap.SwigcptrLms_stream_t(stream)) != 0 {
+		return lastSuiteError()
+	}
+	return nil
+}
+
+func (limeSuiteBackend) StopStream(stream uintptr) error {
+	if limewrap.LMS_StopStream(limewrap.SwigcptrLms_stream_t(stream)) != 0 {
+		return lastSuiteError()
+	}
+	return nil
+}
+
+func (limeSuiteBackend) RecvStream(stream uintptr, buffer []byte, sampleCount int, meta *StreamMeta, timeoutMs uint) (int, error) {
+	m, err := suiteStreamMeta(stream, meta)
+	if err != nil {
+		return -1, err
+	}
+
+	n := limewrap.LMS_RecvStream(limewrap.SwigcptrLms_stream_t(stream), uintptr(unsafe.Pointer(&buffer[0])), int64(sampleCount), m, timeoutMs)
+	if n < 0 {
+		return n, lastSuiteError()
+	}
+
+	meta.Timestamp = m.GetTimestamp()
+	return n, nil
+}
+
+func (limeSuiteBackend) SendStream(stream uintptr, buffer []byte, sampleCount int, meta *StreamMeta, timeoutMs uint) (int, error) {
+	m, err := suiteStreamMeta(stream, meta)
+	if err != nil {
+		return -1, err
+	}
+
+	n := limewrap.LMS_SendStream(limewrap.SwigcptrLms_stream_t(stream), uintptr(unsafe.Pointer(&buffer[0])), int64(sampleCount), m, timeoutMs)
+	if n < 0 {
+		return n, lastSuiteError()
+	}
+
+	return n, nil
+}
+
+func (limeSuiteBackend) GetStreamStatus(stream uintptr) (StreamStatus, error) {
+	var status = createLms_stream_status_t()
+	defer limewrap.DeleteLms_stream_status_t(status)
+
+	if limewrap.LMS_GetStreamStatus(limewrap.SwigcptrLms_stream_t(stream), status) != 0 {
+		return StreamStatus{}, lastSuiteError()
+	}
+
+	return StreamStatus{
+		Active:          status.GetActive(),
+		FifoFilledCount: int(status.GetFifoFilledCount()),
+		FifoSize:        int(status.GetFifoSize()),
+		Underrun:        int(status.GetUnderrun()),
+		Overrun:         int(status.GetOverrun()),
+		DroppedPackets:  int(status.GetDroppedPackets()),
+		SampleRate:      status.GetSampleRate(),
+		LinkRate:        status.GetLinkRate(),
+		Timestamp:       status.GetTimestamp(),
+	}, nil
+}
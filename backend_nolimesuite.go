@@ -0,0 +1,8 @@
+//go:build nolimesuite
+// +build nolimesuite
+
+package limedrv
+
+// defaultBackend is nil when building without LimeSuite.
+// In that case only devices listed by GetDevicesFrom can be opened.
+var defaultBackend Backend
@@ -0,0 +1,157 @@
+package limedrv
+
+import (
+	"math"
+	"runtime"
+)
+
+// CalibrationResult is the result of a calibration run in a channel
+type CalibrationResult struct {
+	// Channel is the channel number that was calibrated
+	Channel int
+	// IsRX is the direction of the channel that was calibrated
+	IsRX bool
+	// Bandwidth is the calibration bandwidth in Hertz
+	Bandwidth float64
+	// Automatic tells if the calibration was triggered by a center frequency or LPF change. See SetAutoCalibration.
+	Automatic bool
+	// Err is the reason of the failure, or nil if the calibration succeeded
+	Err error
+}
+
+// Success returns true if the calibration succeeded
+func (r CalibrationResult) Success() bool {
+	return r.Err == nil
+}
+
+// channelCalibration is the state of the channel in its last successful calibration
+type channelCalibration struct {
+	// bandwidth is the calibration bandwidth or 0 if the channel was never calibrated
+	bandwidth float64
+	// frequency is the LO frequency, that is shared by all channels of the same direction
+	frequency float64
+	lpf       float64
+}
+
+// calibrate calibrates the channel and reports the result to the calibration callback
+func (d *LMSDevice) calibrate(channelNumber int, isRX bool, bandwidth float64, automatic bool) CalibrationResult {
+	var result = CalibrationResult{
+		Channel:   channelNumber,
+		IsRX:      isRX,
+		Bandwidth: bandwidth,
+		Automatic: automatic,
+	}
+
+	ch, err := d.getChannel(channelNumber, isRX)
+	if err != nil {
+		result.Err = err
+	} else if err := d.backend.Calibrate(d.dev, isRX, channelNumber, bandwidth); err != nil {
+		result.Err = d.channelError("calibrate", channelNumber, isRX, err, nil)
+	} else {
+		ch.calibration = channelCalibration{
+			bandwidth: bandwidth,
+			frequency: ch.frequency,
+			lpf:       ch.lpfBandwidth,
+		}
+	}
+
+	if result.Err != nil {
+		d.log(LogError, channelNumber, isRX, result.Err, "calibration with bandwidth %f failed", bandwidth)
+	} else {
+		d.log(LogInfo, channelNumber, isRX, nil, "calibrated with bandwidth %f", bandwidth)
+	}
+
+	if d.calibrationCallback != nil {
+		d.calibrationCallback(result)
+	}
+
+	return result
+}
+
+// recalibrate calibrates the channel again if its center frequency or LPF changed more than the thresholds set by SetAutoCalibration
+func (d *LMSDevice) recalibrate(ch *LMSChannel) error {
+	var c = ch.calibration
+	if c.bandwidth == 0 {
+		// Never calibrated
+		return nil
+	}
+
+	var frequencyChanged = d.autoCalFrequencyThreshold > 0 && math.Abs(ch.frequency-c.frequency) > d.autoCalFrequencyThreshold
+	var lpfChanged = d.autoCalLPFThreshold > 0 && math.Abs(ch.lpfBandwidth-c.lpf) > d.autoCalLPFThreshold
+
+	if !frequencyChanged && !lpfChanged {
+		return nil
+	}
+
+	return d.calibrate(ch.parentIndex, ch.IsRX, c.bandwidth, true).Err
+}
+
+// retuned records the new LO frequency in all channels of the direction, since they share the LO,
+// and calibrates again the ones that moved more than the threshold. The first error is returned.
+func (d *LMSDevice) retuned(isRX bool, frequency float64) error {
+	var err error
+	for _, ch := range d.channels(isRX) {
+		ch.frequency = frequency
+		if e := d.recalibrate(ch); e != nil && err == nil {
+			err = e
+		}
+	}
+	return err
+}
+
+// Calibrate runs the automatic calibration (DC offset and IQ imbalance) of the specified channel for the bandwidth in Hertz.
+// It should be called after setting the sample rate, center frequency and LPF of the channel.
+func (d *LMSDevice) Calibrate(channelNumber int, isRX bool, bandwidth float64) error {
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+	return d.calibrate(channelNumber, isRX, bandwidth, false).Err
+}
+
+// CalibrateAll calibrates all enabled channels for the bandwidth in Hertz and returns the result of each one.
+// The returned error is the first failure, if any.
+func (d *LMSDevice) CalibrateAll(bandwidth float64) ([]CalibrationResult, error) {
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+	var results = make([]CalibrationResult, 0)
+	var err error
+
+	for _, channels := range [][]*LMSChannel{d.RXChannels, d.TXChannels} {
+		for _, ch := range channels {
+			if !ch.enabled {
+				continue
+			}
+			result := d.calibrate(ch.parentIndex, ch.IsRX, bandwidth, false)
+			if result.Err != nil && err == nil {
+				err = result.Err
+			}
+			results = append(results, result)
+		}
+	}
+
+	return results, err
+}
+
+// EnableCalibrationCache enables or disables the LimeSuite calibration cache.
+// When enabled, calibration results are stored by frequency and reused instead of running the calibration again.
+func (d *LMSDevice) EnableCalibrationCache(enabled bool) error {
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+	if err := d.backend.EnableCalibCache(d.dev, enabled); err != nil {
+		return d.deviceError("enable calibration cache", err, nil)
+	}
+	return nil
+}
+
+// SetCalibrationCallback sets a callback that receives the result of every calibration run, including automatic ones.
+func (d *LMSDevice) SetCalibrationCallback(cb func(CalibrationResult)) {
+	d.calibrationCallback = cb
+}
+
+// SetAutoCalibration enables the automatic calibration of already calibrated channels when their center frequency
+// changes more than frequencyThreshold or their analog LPF bandwidth changes more than lpfThreshold (both in Hertz)
+// since the last calibration. The channel is calibrated again with the same bandwidth. A threshold of 0 disables it.
+// Channels of the same direction share the LO, so retuning one channel calibrates again all calibrated channels of the direction.
+func (d *LMSDevice) SetAutoCalibration(frequencyThreshold, lpfThreshold float64) {
+	d.autoCalFrequencyThreshold = frequencyThreshold
+	d.autoCalLPFThreshold = lpfThreshold
+}
@@ -0,0 +1,95 @@
+package limedrv
+
+import "testing"
+
+// simCalibrations returns how many calibrations ran in a channel of a device opened from a SimBackend
+func simCalibrations(t *testing.T, d *LMSDevice, isRX bool, channel int) int {
+	t.Helper()
+	var s = d.backend.(*SimBackend)
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	ch, err := s.channel(d.dev, isRX, channel)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ch.calibrations
+}
+
+func TestAutoCalibration(t *testing.T) {
+	var d = openSim(t, 100e6)
+	defer d.Close()
+	for _, ch := range []*LMSChannel{d.RXChannels[1], d.TXChannels[0]} {
+		if err := ch.Enable(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := d.SetCenterFrequency(0, false, 100e6); err != nil {
+		t.Fatal(err)
+	}
+
+	var automatic []CalibrationResult
+	d.SetCalibrationCallback(func(r CalibrationResult) {
+		if r.Automatic {
+			automatic = append(automatic, r)
+		}
+	})
+	if _, err := d.CalibrateAll(5e6); err != nil {
+		t.Fatal(err)
+	}
+	d.SetAutoCalibration(10e6, 0)
+
+	var expect = func(step string, rx0, rx1, tx0 int) {
+		t.Helper()
+		var got = [3]int{simCalibrations(t, d, true, 0), simCalibrations(t, d, true, 1), simCalibrations(t, d, false, 0)}
+		if got != [3]int{rx0, rx1, tx0} {
+			t.Errorf("%s: expected RX0, RX1 and TX0 calibrated %d, %d and %d times, got %v", step, rx0, rx1, tx0, got)
+		}
+	}
+	expect("calibrate all", 1, 1, 1)
+
+	if err := d.SetCenterFrequency(0, true, 105e6); err != nil {
+		t.Fatal(err)
+	}
+	expect("retune below the threshold", 1, 1, 1)
+
+	// RX channel 1 shares the LO of RX channel 0, but TX channels do not
+	if err := d.SetCenterFrequency(0, true, 120e6); err != nil {
+		t.Fatal(err)
+	}
+	expect("retune above the threshold", 2, 2, 1)
+	if len(automatic) != 2 {
+		t.Errorf("expected 2 automatic calibrations reported, got %d", len(automatic))
+	}
+
+	if _, err := d.SetCenterFrequencies(true, map[int]float64{0: 140e6, 1: 141e6}); err != nil {
+		t.Fatal(err)
+	}
+	expect("frequency plan above the threshold", 3, 3, 1)
+}
+
+func TestCalibrationCache(t *testing.T) {
+	var d = openSim(t, 100e6)
+	defer d.Close()
+	if err := d.EnableCalibrationCache(true); err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 2; i++ {
+		if err := d.Calibrate(0, true, 5e6); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n := simCalibrations(t, d, true, 0); n != 1 {
+		t.Errorf("expected the second calibration to be loaded from the cache, got %d calibrations", n)
+	}
+
+	if err := d.SetCenterFrequency(0, true, 200e6); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Calibrate(0, true, 5e6); err != nil {
+		t.Fatal(err)
+	}
+	if n := simCalibrations(t, d, true, 0); n != 2 {
+		t.Errorf("expected a calibration at a new frequency to run, got %d calibrations", n)
+	}
+}
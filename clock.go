@@ -0,0 +1,129 @@
+package limedrv
+
+import (
+	"fmt"
+	"runtime"
+)
+
+// Clock is a clock of the LMS Device clock tree
+type Clock int
+
+const (
+	// ClockReference is the reference clock of the LMS chip, used by the synthesizers and CGEN
+	ClockReference Clock = iota
+	// ClockSXR is the RX LO synthesizer
+	ClockSXR
+	// ClockSXT is the TX LO synthesizer
+	ClockSXT
+	// ClockCGEN is the clock generator of the ADC, DAC and digital interface. It is defined by the RF sample rate.
+	ClockCGEN
+	// ClockRXTSP is the clock of the RX signal processor (Read Only)
+	ClockRXTSP
+	// ClockTXTSP is the clock of the TX signal processor (Read Only)
+	ClockTXTSP
+	// ClockExternalReference is the frequency of the external reference input (Write Only). See SetExternalReference.
+	ClockExternalReference
+)
+
+var clockNames = map[Clock]string{
+	ClockReference:         "Reference",
+	ClockSXR:               "SXR",
+	ClockSXT:               "SXT",
+	ClockCGEN:              "CGEN",
+	ClockRXTSP:             "RXTSP",
+	ClockTXTSP:             "TXTSP",
+	ClockExternalReference: "ExternalReference",
+}
+
+// String returns the name of the clock
+func (c Clock) String() string {
+	if name, ok := clockNames[c]; ok {
+		return name
+	}
+	return fmt.Sprintf("Clock(%d)", int(c))
+}
+
+// Readable returns true if the frequency of the clock can be read
+func (c Clock) Readable() bool {
+	_, ok := clockNames[c]
+	return ok && c != ClockExternalReference
+}
+
+// Writable returns true if the frequency of the clock can be set
+func (c Clock) Writable() bool {
+	_, ok := clockNames[c]
+	return ok && c != ClockRXTSP && c != ClockTXTSP
+}
+
+// SetClockFrequency sets the frequency in Hertz of a clock of the device.
+// Setting ClockSXR or ClockSXT tunes the LO directly, without the automatic calibration of SetCenterFrequency,
+// and setting ClockCGEN changes the sample rate.
+// Frequencies are the ones of the hardware, without the correction set by SetFrequencyCorrection.
+func (d *LMSDevice) SetClockFrequency(clock Clock, frequency float64) error {
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+	if !clock.Writable() {
+		return d.deviceError(fmt.Sprintf("set clock %s", clock), nil, ErrOutOfRange)
+	}
+
+	if err := d.backend.SetClockFreq(d.dev, clock, frequency); err != nil {
+		return d.deviceError(fmt.Sprintf("set clock %s to %f", clock, frequency), err, nil)
+	}
+
+	if clock == ClockExternalReference {
+		d.externalReference = frequency
+	}
+	return nil
+}
+
+// GetClockFrequency returns the frequency in Hertz of a clock of the device.
+func (d *LMSDevice) GetClockFrequency(clock Clock) (float64, error) {
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+	if !clock.Readable() {
+		return 0, d.deviceError(fmt.Sprintf("get clock %s", clock), nil, ErrOutOfRange)
+	}
+
+	frequency, err := d.backend.GetClockFreq(d.dev, clock)
+	if err != nil {
+		return 0, d.deviceError(fmt.Sprintf("get clock %s", clock), err, nil)
+	}
+	return frequency, nil
+}
+
+// GetClockFrequencies returns the frequency in Hertz of all readable clocks of the device.
+// It can be used to check that the synthesizers are at the expected frequencies before starting.
+func (d *LMSDevice) GetClockFrequencies() (map[Clock]float64, error) {
+	var frequencies = make(map[Clock]float64)
+	for clock := ClockReference; clock <= ClockExternalReference; clock++ {
+		if !clock.Readable() {
+			continue
+		}
+		frequency, err := d.GetClockFrequency(clock)
+		if err != nil {
+			return nil, err
+		}
+		frequencies[clock] = frequency
+	}
+	return frequencies, nil
+}
+
+// SetExternalReference makes the device use the external reference input with the specified frequency in Hertz,
+// for example 10e6 for a GPSDO.
+func (d *LMSDevice) SetExternalReference(frequency float64) error {
+	if frequency <= 0 {
+		return d.deviceError(fmt.Sprintf("set external reference to %f", frequency), nil, ErrOutOfRange)
+	}
+	return d.SetClockFrequency(ClockExternalReference, frequency)
+}
+
+// UseInternalReference makes the device use its internal reference oscillator instead of the external reference input.
+func (d *LMSDevice) UseInternalReference() error {
+	return d.SetClockFrequency(ClockExternalReference, 0)
+}
+
+// GetExternalReference returns the frequency in Hertz of the external reference in use, or 0 if the device uses the internal one.
+// The external reference cannot be read back from the device, so this is the last value set by SetExternalReference.
+func (d *LMSDevice) GetExternalReference() float64 {
+	return d.externalReference
+}
@@ -1,7 +1,5 @@
 package limedrv
 
-import "github.com/racerxdl/limedrv/limewrap"
-
 // Preset of channel IDs by name. To be used in channel calls.
 const (
 	// ChannelA represents the ID of Channel A in LMS Devices ( = 0 )
@@ -32,12 +30,19 @@ const (
 
 const fifoSize = 16384 // Samples
 
+// NCOValueCount is the number of entries in the NCO frequency / phase table of each channel
+const NCOValueCount = 16
+
+const txQueueDepth = 16 // Sample blocks queued by LMSChannel.WriteSamples and LMSChannel.SendBurstAt
+
+const defaultSamplesDepth = 16 // Sample blocks buffered by LMSChannel.Samples
+
 // IQ Formats to be set in IQFormat of LMSDevice. This sets the communication between the LMS Device and the computer.
-var (
+const (
 	// FormatFloat32 defines the output of LMS Device to have samples using 32 bit float
-	FormatFloat32 = limewrap.Lms_stream_tLMS_FMT_F32
+	FormatFloat32 = iota
 	// FormatInt16 defines the output of LMS Device to have samples using 16 bit int
-	FormatInt16 = limewrap.Lms_stream_tLMS_FMT_I16
+	FormatInt16
 	// FormatInt12 defines the output of LMS Device to have samples using 12 bit int
-	FormatInt12 = limewrap.Lms_stream_tLMS_FMT_I12
+	FormatInt12
 )
@@ -0,0 +1,33 @@
+package limedrv
+
+import (
+	"runtime"
+)
+
+// BoardInfo is the information reported by a LMS Device after it is opened (LMS_GetDeviceInfo)
+type BoardInfo struct {
+	DeviceName string
+	// ExpansionName is the name of the expansion board attached to the device, or "UNSUPPORTED" if there is none
+	ExpansionName       string
+	FirmwareVersion     string
+	HardwareVersion     string
+	ProtocolVersion     string
+	BoardSerialNumber   uint64
+	GatewareVersion     string
+	GatewareTargetBoard string
+	// LibraryVersion is the version of LimeSuite (or of the backend) used to talk to the device
+	LibraryVersion string
+}
+
+// Info returns the information reported by the device and the LimeSuite library version.
+// Unlike DeviceInfo, that comes from the device list, it is read from the opened device.
+func (d *LMSDevice) Info() (BoardInfo, error) {
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+	info, err := d.backend.GetDeviceInfo(d.dev)
+	if err != nil {
+		return BoardInfo{}, d.deviceError("get device info", err, nil)
+	}
+	info.LibraryVersion = d.backend.GetLibraryVersion()
+	return info, nil
+}
@@ -0,0 +1,122 @@
+package limedrv
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Sentinel errors that can be matched with errors.Is against any error returned by limedrv.
+var (
+	// ErrDeviceNotFound is returned when the requested device cannot be found or opened
+	ErrDeviceNotFound = errors.New("limedrv: device not found")
+	// ErrOutOfRange is returned when a requested value is outside the range supported by the device
+	ErrOutOfRange = errors.New("limedrv: value out of range")
+	// ErrStreamFailure is returned when a stream cannot be set up, started or read
+	ErrStreamFailure = errors.New("limedrv: stream failure")
+	// ErrInvalidChannel is returned when a channel number does not exist in the device
+	ErrInvalidChannel = errors.New("limedrv: invalid channel")
+	// ErrAntennaNotFound is returned when a antenna port name does not exist in the channel
+	ErrAntennaNotFound = errors.New("limedrv: antenna not found")
+	// ErrNoBandwidth is returned when enabling the digital filter before setting its bandwidth
+	ErrNoBandwidth = errors.New("limedrv: no digital filter bandwidth set")
+	// ErrAdvancedFiltering is returned when using the simple digital filter calls while manual GFIR taps are set
+	ErrAdvancedFiltering = errors.New("limedrv: advanced filtering is enabled")
+	// ErrAlreadyRunning is returned when starting a device that is already running
+	ErrAlreadyRunning = errors.New("limedrv: device already running")
+	// ErrNotRunning is returned when stopping a device that is not running
+	ErrNotRunning = errors.New("limedrv: device not running")
+	// ErrWrongDirection is returned when calling a RX only operation in a TX channel or vice versa
+	ErrWrongDirection = errors.New("limedrv: operation not supported in this channel direction")
+	// ErrAborted is returned when a operation is aborted by its progress callback
+	ErrAborted = errors.New("limedrv: operation aborted")
+	// ErrMultipleDevices is returned when a device selector matches more than one device
+	ErrMultipleDevices = errors.New("limedrv: more than one device matches")
+)
+
+// LMSError is the error returned by every failed operation in a LMS Device.
+// It carries the device and channel the operation was done and the last error message reported by LimeSuite.
+type LMSError struct {
+	// Op is the operation that failed. Example: "set LPF bandwidth"
+	Op string
+	// DeviceName is the name of the device the operation was done
+	DeviceName string
+	// Media is the media of the device the operation was done. Example: "USB 3.0"
+	Media string
+	// Channel is the channel number the operation was done or -1 if the operation is device wide
+	Channel int
+	// IsRX is the direction of the channel. Only meaningful if Channel is not -1
+	IsRX bool
+	// Message is the error message reported by LimeSuite (LMS_GetLastErrorMessage)
+	Message string
+	// Err is the underlying error, usually one of the limedrv sentinel errors. Can be nil.
+	Err error
+}
+
+// Direction returns "RX" or "TX" depending on the channel direction of the error
+func (e *LMSError) Direction() string {
+	if e.IsRX {
+		return "RX"
+	}
+	return "TX"
+}
+
+// Error returns a representation of the error
+func (e *LMSError) Error() string {
+	var str = fmt.Sprintf("failed to %s", e.Op)
+
+	if e.Channel >= 0 {
+		str = fmt.Sprintf("%s on %s channel %d", str, e.Direction(), e.Channel)
+	}
+
+	if e.DeviceName != "" || e.Media != "" {
+		str = fmt.Sprintf("%s in %s at %s", str, e.DeviceName, e.Media)
+	}
+
+	if e.Message != "" {
+		str = fmt.Sprintf("%s: %s", str, e.Message)
+	} else if e.Err != nil {
+		str = fmt.Sprintf("%s: %s", str, e.Err)
+	}
+
+	return "limedrv: " + str
+}
+
+// Unwrap returns the underlying error
+func (e *LMSError) Unwrap() error {
+	return e.Err
+}
+
+// sentinelError is a backend error classified by limedrv as one of its sentinel errors.
+// It matches the sentinel with errors.Is and unwraps to the backend error, so the driver message is kept.
+type sentinelError struct {
+	sentinel error
+	err      error
+}
+
+func (e *sentinelError) Error() string {
+	return e.err.Error()
+}
+
+func (e *sentinelError) Unwrap() error {
+	return e.err
+}
+
+func (e *sentinelError) Is(target error) bool {
+	return target == e.sentinel
+}
+
+// classifyMessage returns the sentinel error that better describes a LimeSuite error message or nil if there is none.
+// The LimeSuite API only returns -1 on failures, so this is a fallback for the errors that limedrv cannot classify
+// by the operation that failed, which are passed as sentinels to deviceError and channelError instead.
+func classifyMessage(message string) error {
+	var m = strings.ToLower(message)
+	switch {
+	case strings.Contains(m, "out of range"), strings.Contains(m, "too high"), strings.Contains(m, "too low"):
+		return ErrOutOfRange
+	case strings.Contains(m, "not found"), strings.Contains(m, "no device"), strings.Contains(m, "not connected"):
+		return ErrDeviceNotFound
+	}
+
+	return nil
+}
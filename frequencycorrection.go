@@ -0,0 +1,59 @@
+package limedrv
+
+import (
+	"fmt"
+	"runtime"
+)
+
+// toHardwareFrequency converts a frequency in Hertz to the value to request to the hardware,
+// compensating the reference oscillator error set by SetFrequencyCorrection
+func (d *LMSDevice) toHardwareFrequency(frequency float64) float64 {
+	return frequency / (1 + d.ppmCorrection/1e6)
+}
+
+// fromHardwareFrequency converts a frequency in Hertz reported by the hardware to the actual frequency,
+// compensating the reference oscillator error set by SetFrequencyCorrection
+func (d *LMSDevice) fromHardwareFrequency(frequency float64) float64 {
+	return frequency * (1 + d.ppmCorrection/1e6)
+}
+
+// SetVCTCXOTrim sets the trim value of the DAC that tunes the reference oscillator (VCTCXO) of the device.
+// This corrects the reference oscillator in hardware, so it also affects everything clocked by it.
+// The range of the value and how much it moves the frequency depend on the board.
+func (d *LMSDevice) SetVCTCXOTrim(value uint16) error {
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+	if err := d.backend.VCTCXOWrite(d.dev, value); err != nil {
+		return d.deviceError(fmt.Sprintf("set VCTCXO trim to %d", value), err, nil)
+	}
+	return nil
+}
+
+// GetVCTCXOTrim returns the trim value of the DAC that tunes the reference oscillator (VCTCXO) of the device.
+func (d *LMSDevice) GetVCTCXOTrim() (uint16, error) {
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+	value, err := d.backend.VCTCXORead(d.dev)
+	if err != nil {
+		return 0, d.deviceError("get VCTCXO trim", err, nil)
+	}
+	return value, nil
+}
+
+// SetFrequencyCorrection sets the software correction of the reference oscillator error in parts per million.
+// A positive value means the reference oscillator runs faster than its nominal frequency.
+// The correction is applied by SetCenterFrequency, SetCenterFrequencies and SetSampleRate, and reverted by
+// GetCenterFrequency and GetSampleRate, so the frequencies requested and returned are the ones on the air.
+// It only affects frequencies set after this call. The NCO tables set by SetNCOFrequencies are not corrected.
+func (d *LMSDevice) SetFrequencyCorrection(ppm float64) error {
+	if ppm <= -1e6 {
+		return d.deviceError(fmt.Sprintf("set frequency correction to %f ppm", ppm), nil, ErrOutOfRange)
+	}
+	d.ppmCorrection = ppm
+	return nil
+}
+
+// GetFrequencyCorrection returns the software correction of the reference oscillator error in parts per million.
+func (d *LMSDevice) GetFrequencyCorrection() float64 {
+	return d.ppmCorrection
+}
@@ -0,0 +1,112 @@
+package limedrv
+
+import (
+	"fmt"
+	"math"
+	"runtime"
+	"sort"
+)
+
+// FrequencyPlan is the LO and NCO configuration chosen to tune channels that share the same LO to different center frequencies
+type FrequencyPlan struct {
+	// IsRX is the direction of the channels
+	IsRX bool
+	// LOFrequency is the frequency in Hertz of the LO shared by the channels
+	LOFrequency float64
+	// NCOOffsets is the NCO offset in Hertz from the LO for each channel number.
+	// Channels with offset 0 are tuned by the LO and have the NCO disabled.
+	NCOOffsets map[int]float64
+}
+
+// planFrequencies chooses a LO in the middle of the requested center frequencies and the NCO offset of each channel.
+// The NCO offset plus half the host sample rate should fit in the RF sample rate Nyquist band, otherwise the channel would be aliased.
+func planFrequencies(isRX bool, frequencies map[int]float64, hostSampleRate, rfSampleRate float64) (FrequencyPlan, error) {
+	var plan = FrequencyPlan{
+		IsRX:       isRX,
+		NCOOffsets: make(map[int]float64, len(frequencies)),
+	}
+
+	if len(frequencies) == 0 {
+		return plan, fmt.Errorf("no center frequencies to plan")
+	}
+
+	var min, max = math.Inf(1), math.Inf(-1)
+	for _, f := range frequencies {
+		min = math.Min(min, f)
+		max = math.Max(max, f)
+	}
+
+	var maxOffset = (rfSampleRate - hostSampleRate) / 2
+	if max-min > 2*maxOffset {
+		return plan, fmt.Errorf("channels are %.0f Hz apart, but a RF sample rate of %.0f sps with host sample rate of %.0f sps allows at most %.0f Hz (increase the oversample in SetSampleRate)",
+			max-min, rfSampleRate, hostSampleRate, 2*maxOffset)
+	}
+
+	plan.LOFrequency = (min + max) / 2
+	for channel, f := range frequencies {
+		plan.NCOOffsets[channel] = f - plan.LOFrequency
+	}
+
+	return plan, nil
+}
+
+// SetCenterFrequencies tunes channels of the same direction, that share the same LO, to different center frequencies.
+// frequencies is the desired center frequency in Hertz for each channel number.
+// The LO is placed between the frequencies and each channel is shifted by its NCO, so the channels can be apart
+// up to the RF sample rate minus the host sample rate (see SetSampleRate oversample).
+// The NCO table of the channels is overwritten. Channels not in frequencies have the NCO disabled and follow the new LO.
+// GetCenterFrequency returns the planned frequency of each channel, until SetCenterFrequency retunes the LO again.
+func (d *LMSDevice) SetCenterFrequencies(isRX bool, frequencies map[int]float64) (FrequencyPlan, error) {
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+
+	var channels = make([]int, 0, len(frequencies))
+	for channelNumber := range frequencies {
+		if _, err := d.getChannel(channelNumber, isRX); err != nil {
+			return FrequencyPlan{}, err
+		}
+		channels = append(channels, channelNumber)
+	}
+	sort.Ints(channels)
+
+	host, rf, err := d.backend.GetSampleRate(d.dev, isRX, 0)
+	if err != nil {
+		return FrequencyPlan{}, d.deviceError("get sample rate", err, nil)
+	}
+
+	plan, err := planFrequencies(isRX, frequencies, d.fromHardwareFrequency(host), d.fromHardwareFrequency(rf))
+	if err != nil {
+		return plan, d.deviceError("plan center frequencies", err, ErrOutOfRange)
+	}
+
+	if len(channels) == 0 {
+		return plan, nil
+	}
+
+	if err := d.backend.SetLOFrequency(d.dev, isRX, channels[0], d.toHardwareFrequency(plan.LOFrequency)); err != nil {
+		return plan, d.deviceError(fmt.Sprintf("set LO frequency to %.0f", plan.LOFrequency), err, nil)
+	}
+
+	for _, ch := range d.channels(isRX) {
+		var offset = plan.NCOOffsets[ch.parentIndex]
+		if offset == 0 {
+			if err := d.setNCOIndex(ch, -1, false); err != nil {
+				return plan, err
+			}
+			continue
+		}
+
+		var table = make([]float64, NCOValueCount)
+		table[0] = d.toHardwareFrequency(math.Abs(offset))
+		if err := d.backend.SetNCOFrequency(d.dev, isRX, ch.parentIndex, table, 0); err != nil {
+			return plan, d.channelError("set NCO frequencies", ch.parentIndex, isRX, err, nil)
+		}
+		ch.ncoPhaseMode = false
+		// RX channels are tuned above the LO by down converting, TX channels by up converting
+		if err := d.setNCOIndex(ch, 0, (offset > 0) == isRX); err != nil {
+			return plan, err
+		}
+	}
+
+	return plan, d.retuned(isRX, plan.LOFrequency)
+}
@@ -0,0 +1,111 @@
+package limedrv
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestPlanFrequencies(t *testing.T) {
+	var tests = []struct {
+		name        string
+		frequencies map[int]float64
+		lo          float64
+		offsets     map[int]float64
+		fails       bool
+	}{
+		{"single channel", map[int]float64{0: 100e6}, 100e6, map[int]float64{0: 0}, false},
+		{"midpoint", map[int]float64{0: 100e6, 1: 101e6}, 100.5e6, map[int]float64{0: -0.5e6, 1: 0.5e6}, false},
+		{"at the limit", map[int]float64{0: 100e6, 1: 103e6}, 101.5e6, map[int]float64{0: -1.5e6, 1: 1.5e6}, false},
+		{"too far apart", map[int]float64{0: 100e6, 1: 103.1e6}, 0, nil, true},
+		{"no channels", map[int]float64{}, 0, nil, true},
+	}
+
+	for _, tt := range tests {
+		// 4 Msps at RF with 1 Msps at the host allows offsets up to 1.5 MHz
+		plan, err := planFrequencies(true, tt.frequencies, 1e6, 4e6)
+		if tt.fails {
+			if err == nil {
+				t.Errorf("%s: expected error, got plan %+v", tt.name, plan)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if plan.LOFrequency != tt.lo {
+			t.Errorf("%s: expected LO at %f, got %f", tt.name, tt.lo, plan.LOFrequency)
+		}
+		for channel, offset := range tt.offsets {
+			if plan.NCOOffsets[channel] != offset {
+				t.Errorf("%s: expected channel %d offset %f, got %f", tt.name, channel, offset, plan.NCOOffsets[channel])
+			}
+		}
+	}
+}
+
+// simTunedFrequency returns the frequency received by a simulated channel, the LO shifted by its NCO
+func simTunedFrequency(t *testing.T, d *LMSDevice, channel int) float64 {
+	t.Helper()
+	var s = d.backend.(*SimBackend)
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	ch, err := s.channel(d.dev, true, channel)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ch.tunedFrequency()
+}
+
+func TestSetCenterFrequencies(t *testing.T) {
+	var d = openSim(t, 100e6)
+	defer d.Close()
+	if err := d.SetSampleRate(1e6, 4); err != nil {
+		t.Fatal(err)
+	}
+
+	plan, err := d.SetCenterFrequencies(true, map[int]float64{0: 100e6, 1: 101e6})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if plan.LOFrequency != 100.5e6 {
+		t.Errorf("expected LO at 100.5 MHz, got %f", plan.LOFrequency)
+	}
+	for channel, expected := range []float64{100e6, 101e6} {
+		if f, err := d.GetCenterFrequency(channel, true); err != nil || f != expected {
+			t.Errorf("channel %d: expected center frequency %f, got %f (%v)", channel, expected, f, err)
+		}
+		if f := simTunedFrequency(t, d, channel); f != expected {
+			t.Errorf("channel %d: expected the NCO to tune to %f, got %f", channel, expected, f)
+		}
+	}
+
+	// Channels left out of the plan follow the LO
+	if _, err := d.SetCenterFrequencies(true, map[int]float64{0: 200e6}); err != nil {
+		t.Fatal(err)
+	}
+	if f, err := d.GetCenterFrequency(1, true); err != nil || f != 200e6 {
+		t.Errorf("expected channel 1 to follow the LO to 200 MHz, got %f (%v)", f, err)
+	}
+	if _, err := d.GetNCOIndex(1, true); err == nil {
+		t.Errorf("expected the NCO of channel 1 to be disabled")
+	}
+
+	_, err = d.SetCenterFrequencies(true, map[int]float64{0: 100e6, 1: 104e6})
+	if !errors.Is(err, ErrOutOfRange) {
+		t.Errorf("expected ErrOutOfRange for channels 4 MHz apart, got %v", err)
+	}
+
+	// SetCenterFrequency retunes the LO and disables the NCO of all channels
+	if _, err := d.SetCenterFrequencies(true, map[int]float64{0: 100e6, 1: 101e6}); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.SetCenterFrequency(1, true, 300e6); err != nil {
+		t.Fatal(err)
+	}
+	for channel := 0; channel < 2; channel++ {
+		if f := simTunedFrequency(t, d, channel); f != 300e6 {
+			t.Errorf("channel %d: expected 300 MHz after SetCenterFrequency, got %f", channel, f)
+		}
+	}
+}
@@ -0,0 +1,233 @@
+package limedrv
+
+import (
+	"fmt"
+	"runtime"
+)
+
+// gpioBankSize is the number of pins in each GPIO bank
+const gpioBankSize = 8
+
+// GPIOMask is a bit mask of the pins in a GPIO bank, where bit N represents pin N of the bank
+type GPIOMask uint8
+
+// GPIOMaskOf returns the mask with the specified pins of a bank (0 to 7) set
+func GPIOMaskOf(pins ...int) GPIOMask {
+	var m GPIOMask
+	for _, pin := range pins {
+		m = m.Set(pin)
+	}
+	return m
+}
+
+// Has returns true if the pin is set in the mask
+func (m GPIOMask) Has(pin int) bool {
+	return m&(1<<uint(pin)) != 0
+}
+
+// Set returns the mask with the pin set
+func (m GPIOMask) Set(pin int) GPIOMask {
+	return m | 1<<uint(pin)
+}
+
+// Clear returns the mask with the pin cleared
+func (m GPIOMask) Clear(pin int) GPIOMask {
+	return m &^ (1 << uint(pin))
+}
+
+// String returns the mask in binary, with pin 7 first
+func (m GPIOMask) String() string {
+	return fmt.Sprintf("%08b", uint8(m))
+}
+
+// LMSGPIO is the GPIO header of a LMS Device. Pins are numbered from 0, with pin N being in bank N / 8.
+// Use LMSDevice.GPIO to access it.
+type LMSGPIO struct {
+	parent *LMSDevice
+
+	pttPins       []int
+	pttActiveHigh bool
+}
+
+// gpioError returns a LMSError for a GPIO operation
+func (g *LMSGPIO) gpioError(op string, err, sentinel error) error {
+	return g.parent.deviceError(op, err, sentinel)
+}
+
+// checkPin returns a ErrOutOfRange error if the pin is negative
+func (g *LMSGPIO) checkPin(op string, pin int) error {
+	if pin < 0 {
+		return g.gpioError(fmt.Sprintf("%s %d", op, pin), nil, ErrOutOfRange)
+	}
+	return nil
+}
+
+// readBanks reads the banks 0 to bank from the levels (or directions if dir is set) of the GPIO
+func (g *LMSGPIO) readBanks(op string, bank int, dir bool) ([]byte, error) {
+	if bank < 0 {
+		return nil, g.gpioError(fmt.Sprintf("%s bank %d", op, bank), nil, ErrOutOfRange)
+	}
+
+	var banks []byte
+	var err error
+	if dir {
+		banks, err = g.parent.backend.GPIODirRead(g.parent.dev, bank+1)
+	} else {
+		banks, err = g.parent.backend.GPIORead(g.parent.dev, bank+1)
+	}
+
+	if err != nil {
+		return nil, g.gpioError(fmt.Sprintf("%s bank %d", op, bank), err, nil)
+	}
+	return banks, nil
+}
+
+// writeBank writes the bank levels (or directions if dir is set) keeping the other banks as they are
+func (g *LMSGPIO) writeBank(op string, bank int, mask GPIOMask, dir bool) error {
+	banks, err := g.readBanks(op, bank, dir)
+	if err != nil {
+		return err
+	}
+
+	banks[bank] = byte(mask)
+	if dir {
+		err = g.parent.backend.GPIODirWrite(g.parent.dev, banks)
+	} else {
+		err = g.parent.backend.GPIOWrite(g.parent.dev, banks)
+	}
+
+	if err != nil {
+		return g.gpioError(fmt.Sprintf("%s bank %d", op, bank), err, nil)
+	}
+	return nil
+}
+
+// ReadBank returns the levels of the pins in the bank. A set bit means high level.
+func (g *LMSGPIO) ReadBank(bank int) (GPIOMask, error) {
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+	banks, err := g.readBanks("read GPIO", bank, false)
+	if err != nil {
+		return 0, err
+	}
+	return GPIOMask(banks[bank]), nil
+}
+
+// WriteBank sets the levels of the output pins in the bank. A set bit means high level.
+func (g *LMSGPIO) WriteBank(bank int, levels GPIOMask) error {
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+	return g.writeBank("write GPIO", bank, levels, false)
+}
+
+// ReadBankDirection returns the direction of the pins in the bank. A set bit means output.
+func (g *LMSGPIO) ReadBankDirection(bank int) (GPIOMask, error) {
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+	banks, err := g.readBanks("read GPIO direction", bank, true)
+	if err != nil {
+		return 0, err
+	}
+	return GPIOMask(banks[bank]), nil
+}
+
+// WriteBankDirection sets the direction of the pins in the bank. A set bit means output.
+func (g *LMSGPIO) WriteBankDirection(bank int, outputs GPIOMask) error {
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+	return g.writeBank("write GPIO direction", bank, outputs, true)
+}
+
+// Read returns true if the pin is at high level
+func (g *LMSGPIO) Read(pin int) (bool, error) {
+	if err := g.checkPin("read GPIO pin", pin); err != nil {
+		return false, err
+	}
+
+	levels, err := g.ReadBank(pin / gpioBankSize)
+	if err != nil {
+		return false, err
+	}
+	return levels.Has(pin % gpioBankSize), nil
+}
+
+// Write sets the level of a output pin, keeping the other pins as they are
+func (g *LMSGPIO) Write(pin int, high bool) error {
+	if err := g.checkPin("write GPIO pin", pin); err != nil {
+		return err
+	}
+
+	var bank = pin / gpioBankSize
+	levels, err := g.ReadBank(bank)
+	if err != nil {
+		return err
+	}
+
+	if high {
+		levels = levels.Set(pin % gpioBankSize)
+	} else {
+		levels = levels.Clear(pin % gpioBankSize)
+	}
+	return g.WriteBank(bank, levels)
+}
+
+// IsOutput returns true if the pin is configured as output
+func (g *LMSGPIO) IsOutput(pin int) (bool, error) {
+	if err := g.checkPin("read GPIO pin direction", pin); err != nil {
+		return false, err
+	}
+
+	outputs, err := g.ReadBankDirection(pin / gpioBankSize)
+	if err != nil {
+		return false, err
+	}
+	return outputs.Has(pin % gpioBankSize), nil
+}
+
+// SetOutput configures the pin as output if output is true, or as input otherwise, keeping the other pins as they are
+func (g *LMSGPIO) SetOutput(pin int, output bool) error {
+	if err := g.checkPin("write GPIO pin direction", pin); err != nil {
+		return err
+	}
+
+	var bank = pin / gpioBankSize
+	outputs, err := g.ReadBankDirection(bank)
+	if err != nil {
+		return err
+	}
+
+	if output {
+		outputs = outputs.Set(pin % gpioBankSize)
+	} else {
+		outputs = outputs.Clear(pin % gpioBankSize)
+	}
+	return g.WriteBankDirection(bank, outputs)
+}
+
+// SetPTT configures pins to follow the TX state, like a external PTT.
+// The pins are configured as output and driven to the active level when the device starts with TX channels enabled,
+// and back to the inactive level when it stops. Calling it without pins disables it.
+func (g *LMSGPIO) SetPTT(activeHigh bool, pins ...int) error {
+	for _, pin := range pins {
+		if err := g.SetOutput(pin, true); err != nil {
+			return err
+		}
+		if err := g.Write(pin, !activeHigh); err != nil {
+			return err
+		}
+	}
+
+	g.pttPins = pins
+	g.pttActiveHigh = activeHigh
+	return nil
+}
+
+// ptt drives the PTT pins to the active level if active is true, or to the inactive level otherwise
+func (g *LMSGPIO) ptt(active bool) error {
+	for _, pin := range g.pttPins {
+		if err := g.Write(pin, active == g.pttActiveHigh); err != nil {
+			return err
+		}
+	}
+	return nil
+}
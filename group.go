@@ -0,0 +1,448 @@
+package limedrv
+
+import (
+	"fmt"
+	"math"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// groupMaxBlocks is how many blocks a channel of a DeviceGroup can be ahead of the others before the late ones are filled with zeros
+const groupMaxBlocks = 16
+
+// maxBuffered returns how many samples a channel can be ahead of the others.
+// Devices deliver up to fifoSize samples at once, so group blocks smaller than that count as fifoSize.
+func (g *DeviceGroup) maxBuffered() int64 {
+	if g.blockSize < fifoSize {
+		return groupMaxBlocks * fifoSize
+	}
+	return groupMaxBlocks * int64(g.blockSize)
+}
+
+// GroupBlock is a set of sample blocks, one of each enabled RX channel of a DeviceGroup, that start at the same group timestamp
+type GroupBlock struct {
+	// Timestamp is the group timestamp of the first sample, in samples since the group started
+	Timestamp uint64
+	// Blocks has the block of each enabled RX channel, ordered by device and channel number
+	Blocks []GroupSampleBlock
+}
+
+// GroupSampleBlock is the block of a RX channel in a GroupBlock. Its Timestamp is the one of the device sample counter.
+type GroupSampleBlock struct {
+	// Device is the index of the device in DeviceGroup.Devices
+	Device int
+	SampleBlock
+}
+
+// Misalignment is a discontinuity found in the samples of a RX channel of a DeviceGroup
+type Misalignment struct {
+	// Device is the index of the device in DeviceGroup.Devices
+	Device int
+	// Channel is the channel number
+	Channel int
+	// Timestamp is the group timestamp where the discontinuity was found
+	Timestamp uint64
+	// Samples is the size of the discontinuity. Positive values are missing samples (dropped by the device or a slow consumer),
+	// that are replaced by zeros. If more than the group buffer is missing, the buffered samples of all channels are discarded
+	// and the group aligns them again from the next blocks.
+	// Negative values are samples that arrived too late for their group block and were discarded.
+	Samples int64
+}
+
+// DeviceGroup runs several LMS Devices together and delivers the samples of all their enabled RX channels aligned in time.
+//
+// LimeSuite has no way to share the sample counter between devices, so the group measures the offset between the
+// sample counter of each device and the time it was started, and rebuilds the blocks of every channel at the same group timestamps.
+// The offset is measured with the host clock, so it is limited by the USB transfer jitter and is not sample accurate.
+type DeviceGroup struct {
+	// Devices are the devices of the group, in the order they were opened
+	Devices []*LMSDevice
+
+	blockSize            int
+	samples              chan GroupBlock
+	misalignmentCallback func(Misalignment)
+	droppedBlocks        uint64
+
+	running bool
+	stop    chan struct{}
+	wg      sync.WaitGroup
+}
+
+// groupStream is the state of a RX channel in the DeviceGroup alignment
+type groupStream struct {
+	device  int
+	channel *LMSChannel
+	started bool
+	// next is the group timestamp after the last buffered sample
+	next   int64
+	buffer []complex64
+}
+
+// start returns the group timestamp of the first buffered sample
+func (s *groupStream) start() int64 {
+	return s.next - int64(len(s.buffer))
+}
+
+// groupInput is a block received from a RX channel of the group
+type groupInput struct {
+	stream   int
+	block    SampleBlock
+	received time.Time
+}
+
+// OpenGroup opens all devices and returns a DeviceGroup with them.
+// If any device fails to open, the ones already opened are closed.
+func OpenGroup(devices []DeviceInfo) (*DeviceGroup, error) {
+	var g = &DeviceGroup{
+		blockSize: fifoSize,
+		samples:   make(chan GroupBlock, defaultSamplesDepth),
+	}
+
+	for _, info := range devices {
+		d, err := Open(info)
+		if err != nil {
+			g.Close()
+			return nil, err
+		}
+		g.Devices = append(g.Devices, d)
+	}
+
+	return g, nil
+}
+
+// SetBlockSize sets the number of samples of each block delivered by Samples. It cannot be changed while the group is running.
+func (g *DeviceGroup) SetBlockSize(size int) error {
+	if g.running {
+		return ErrAlreadyRunning
+	}
+	if size <= 0 {
+		return fmt.Errorf("limedrv: group block size %d: %w", size, ErrOutOfRange)
+	}
+	g.blockSize = size
+	return nil
+}
+
+// SetMisalignmentCallback sets a callback that is called for each discontinuity found in the samples of the group.
+// It is called from the goroutine that aligns the blocks, so it should not block.
+func (g *DeviceGroup) SetMisalignmentCallback(cb func(Misalignment)) {
+	g.misalignmentCallback = cb
+}
+
+// Samples returns the Go channel that receives the aligned blocks while the group is running.
+// If the consumer falls behind, blocks are dropped and counted in DroppedBlocks.
+func (g *DeviceGroup) Samples() <-chan GroupBlock {
+	return g.samples
+}
+
+// DroppedBlocks returns how many aligned blocks were dropped because the consumer of Samples was too slow
+func (g *DeviceGroup) DroppedBlocks() uint64 {
+	return atomic.LoadUint64(&g.droppedBlocks)
+}
+
+// Start starts all devices of the group back to back and the alignment of their samples.
+// All devices should have the same sample rate and at least one enabled RX channel in the group.
+func (g *DeviceGroup) Start() error {
+	if g.running {
+		return ErrAlreadyRunning
+	}
+
+	if len(g.Devices) == 0 {
+		return fmt.Errorf("limedrv: device group is empty: %w", ErrDeviceNotFound)
+	}
+
+	sampleRate, _, err := g.Devices[0].GetSampleRate()
+	if err != nil {
+		return err
+	}
+
+	var streams []*groupStream
+	for i, d := range g.Devices {
+		rate, _, err := d.GetSampleRate()
+		if err != nil {
+			return err
+		}
+		if math.Abs(rate-sampleRate) > sampleRate*1e-6 {
+			return d.deviceError(fmt.Sprintf("start group with sample rate %f (group sample rate is %f)", rate, sampleRate), nil, ErrOutOfRange)
+		}
+
+		for _, ch := range d.RXChannels {
+			if ch.enabled {
+				streams = append(streams, &groupStream{device: i, channel: ch})
+			}
+		}
+	}
+
+	if len(streams) == 0 {
+		return g.Devices[0].deviceError("start group without enabled RX channels", nil, ErrStreamFailure)
+	}
+
+	// Discard blocks left from a previous run, since their timestamps are from another time base
+	for _, s := range streams {
+		for drained := false; !drained; {
+			select {
+			case <-s.channel.Samples():
+			default:
+				drained = true
+			}
+		}
+	}
+	for drained := false; !drained; {
+		select {
+		case <-g.samples:
+		default:
+			drained = true
+		}
+	}
+
+	g.stop = make(chan struct{})
+	var input = make(chan groupInput)
+	var start = time.Now()
+
+	for i, d := range g.Devices {
+		if err := d.Start(); err != nil {
+			for _, started := range g.Devices[:i] {
+				started.Stop()
+			}
+			return err
+		}
+	}
+
+	g.running = true
+	for i, s := range streams {
+		g.wg.Add(1)
+		go g.forwardLoop(i, s.channel.Samples(), input)
+	}
+	g.wg.Add(1)
+	go g.alignLoop(streams, input, start, sampleRate)
+
+	return nil
+}
+
+// Stop stops the alignment and all devices of the group. The first error is returned, but all devices are stopped.
+func (g *DeviceGroup) Stop() error {
+	if !g.running {
+		return ErrNotRunning
+	}
+
+	g.running = false
+	close(g.stop)
+	g.wg.Wait()
+
+	var err error
+	for _, d := range g.Devices {
+		if e := d.Stop(); e != nil && err == nil {
+			err = e
+		}
+	}
+	return err
+}
+
+// Close stops the group if it is running and closes all devices. The group will be unusable after this call.
+func (g *DeviceGroup) Close() error {
+	var err error
+	if g.running {
+		err = g.Stop()
+	}
+
+	for _, d := range g.Devices {
+		if e := d.Close(); e != nil && err == nil {
+			err = e
+		}
+	}
+	g.Devices = nil
+	return err
+}
+
+// forwardLoop sends the blocks of a RX channel to the align loop with the time they were received
+func (g *DeviceGroup) forwardLoop(stream int, samples <-chan SampleBlock, input chan<- groupInput) {
+	defer g.wg.Done()
+	for {
+		select {
+		case <-g.stop:
+			return
+		case block, ok := <-samples:
+			if !ok {
+				// The device was stopped
+				return
+			}
+			select {
+			case input <- groupInput{stream: stream, block: block, received: time.Now()}:
+			case <-g.stop:
+				return
+			}
+		}
+	}
+}
+
+// alignLoop places the blocks of each RX channel in the group timeline and emits the aligned blocks
+func (g *DeviceGroup) alignLoop(streams []*groupStream, input <-chan groupInput, start time.Time, sampleRate float64) {
+	defer g.wg.Done()
+
+	// offsets are added to the sample counter of each device to get the group timestamp
+	var offsets = make(map[int]int64)
+	var cursor int64 = -1
+	var maxGap = g.maxBuffered()
+
+	for {
+		var in groupInput
+		select {
+		case <-g.stop:
+			return
+		case in = <-input:
+		}
+
+		var s = streams[in.stream]
+		offset, ok := offsets[s.device]
+		if !ok {
+			// The last sample of the first block of the device was received now
+			var received = int64(in.received.Sub(start).Seconds() * sampleRate)
+			offset = received - int64(in.block.Timestamp) - int64(len(in.block.Data))
+			offsets[s.device] = offset
+		}
+
+		var data = in.block.Data
+		var timestamp = int64(in.block.Timestamp) + offset
+		if !s.started {
+			s.started = true
+			s.next = timestamp
+		}
+
+		if gap := timestamp - s.next; gap > maxGap {
+			// Too many samples are missing to fill them with zeros, so the group starts again like in Start
+			g.misaligned(s, s.next, gap)
+			s.channel.parent.log(LogWarning, s.channel.parentIndex, true, nil, "group lost the alignment, aligning the channels again")
+			for _, s := range streams {
+				s.started = false
+				s.buffer = s.buffer[:0]
+			}
+			cursor = -1
+			s.started = true
+			s.next = timestamp
+		} else if timestamp > s.next {
+			g.misaligned(s, s.next, timestamp-s.next)
+			s.buffer = append(s.buffer, make([]complex64, timestamp-s.next)...)
+			s.next = timestamp
+		} else if timestamp < s.next {
+			var late = s.next - timestamp
+			g.misaligned(s, timestamp, -late)
+			if late >= int64(len(data)) {
+				continue
+			}
+			data = data[late:]
+		}
+
+		s.buffer = append(s.buffer, data...)
+		s.next += int64(len(data))
+
+		cursor = g.emitBlocks(streams, offsets, cursor)
+	}
+}
+
+// emitBlocks sends all group blocks available in the streams from cursor and returns the new cursor
+func (g *DeviceGroup) emitBlocks(streams []*groupStream, offsets map[int]int64, cursor int64) int64 {
+	var blockSize = int64(g.blockSize)
+	var maxBuffered = g.maxBuffered()
+
+	for _, s := range streams {
+		if !s.started {
+			// Keep only the most recent samples until all channels are receiving
+			for _, s := range streams {
+				if int64(len(s.buffer)) > maxBuffered {
+					s.buffer = s.buffer[:copy(s.buffer, s.buffer[int64(len(s.buffer))-maxBuffered:])]
+				}
+			}
+			return cursor
+		}
+	}
+
+	if cursor < 0 {
+		// The group starts when all channels have samples
+		for _, s := range streams {
+			if s.start() > cursor {
+				cursor = s.start()
+			}
+		}
+		if cursor < 0 {
+			cursor = 0
+		}
+	}
+
+	for {
+		var ready, ahead = true, false
+		for _, s := range streams {
+			if drop := cursor - s.start(); drop >= int64(len(s.buffer)) {
+				s.buffer = s.buffer[:0]
+			} else if drop > 0 {
+				s.buffer = s.buffer[:copy(s.buffer, s.buffer[drop:])]
+			}
+			ready = ready && s.next >= cursor+blockSize
+			ahead = ahead || s.next-cursor > maxBuffered
+		}
+
+		if !ready && !ahead {
+			return cursor
+		}
+
+		var block = GroupBlock{
+			Timestamp: uint64(cursor),
+			Blocks:    make([]GroupSampleBlock, len(streams)),
+		}
+
+		for i, s := range streams {
+			if s.next < cursor+blockSize {
+				// Other channels are too far ahead, so this one lost samples
+				var from = s.next
+				if from < cursor {
+					from = cursor
+				}
+				g.misaligned(s, from, cursor+blockSize-from)
+				s.buffer = append(s.buffer, make([]complex64, cursor+blockSize-from)...)
+				s.next = cursor + blockSize
+			}
+
+			var data = make([]complex64, blockSize)
+			copy(data, s.buffer)
+			s.buffer = s.buffer[:copy(s.buffer, s.buffer[blockSize:])]
+
+			block.Blocks[i] = GroupSampleBlock{
+				Device: s.device,
+				SampleBlock: SampleBlock{
+					Channel:   s.channel.parentIndex,
+					Timestamp: uint64(cursor - offsets[s.device]),
+					Data:      data,
+				},
+			}
+		}
+		cursor += blockSize
+
+		select {
+		case g.samples <- block:
+		default:
+			atomic.AddUint64(&g.droppedBlocks, 1)
+		}
+	}
+}
+
+// misaligned reports a discontinuity of samples in a stream to the logger and the misalignment callback
+func (g *DeviceGroup) misaligned(s *groupStream, timestamp, samples int64) {
+	if timestamp < 0 {
+		timestamp = 0
+	}
+
+	if samples > 0 {
+		s.channel.parent.log(LogWarning, s.channel.parentIndex, true, nil, "group is missing %d samples at timestamp %d", samples, timestamp)
+	} else {
+		s.channel.parent.log(LogWarning, s.channel.parentIndex, true, nil, "group discarded %d late samples at timestamp %d", -samples, timestamp)
+	}
+
+	if g.misalignmentCallback == nil {
+		return
+	}
+	g.misalignmentCallback(Misalignment{
+		Device:    s.device,
+		Channel:   s.channel.parentIndex,
+		Timestamp: uint64(timestamp),
+		Samples:   samples,
+	})
+}
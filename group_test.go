@@ -0,0 +1,137 @@
+package limedrv
+
+import (
+	"testing"
+	"time"
+)
+
+const groupTestBlockSize = 1024
+
+// openSimGroup opens a DeviceGroup with a device of each SimBackend, all with RX channel 0 enabled.
+// The group should be closed by the caller.
+func openSimGroup(t *testing.T, backends ...*SimBackend) *DeviceGroup {
+	t.Helper()
+	var devices []DeviceInfo
+	for _, backend := range backends {
+		list, err := GetDevicesFrom(backend)
+		if err != nil {
+			t.Fatal(err)
+		}
+		devices = append(devices, list...)
+	}
+
+	g, err := OpenGroup(devices)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, d := range g.Devices {
+		if err := d.RXChannels[0].Enable(); err != nil {
+			g.Close()
+			t.Fatal(err)
+		}
+	}
+	if err := g.SetBlockSize(groupTestBlockSize); err != nil {
+		g.Close()
+		t.Fatal(err)
+	}
+	return g
+}
+
+// receiveGroupBlocks returns the next count blocks of the group
+func receiveGroupBlocks(t *testing.T, g *DeviceGroup, count int) []GroupBlock {
+	t.Helper()
+	var blocks []GroupBlock
+	for len(blocks) < count {
+		select {
+		case block := <-g.Samples():
+			blocks = append(blocks, block)
+		case <-time.After(5 * time.Second):
+			t.Fatalf("received %d of %d group blocks", len(blocks), count)
+		}
+	}
+	return blocks
+}
+
+// waitMisalignment returns the next misalignment reported to the channel
+func waitMisalignment(t *testing.T, misalignments <-chan Misalignment) Misalignment {
+	t.Helper()
+	select {
+	case m := <-misalignments:
+		return m
+	case <-time.After(5 * time.Second):
+		t.Fatal("no misalignment reported")
+	}
+	return Misalignment{}
+}
+
+func TestGroupAligned(t *testing.T) {
+	var tone = SimSignal{Type: SimTone, Frequency: 100.128e6, Amplitude: 0.5}
+	var g = openSimGroup(t, NewSimBackend(tone), NewSimBackend(tone))
+	defer g.Close()
+
+	var misalignments = make(chan Misalignment, 16)
+	g.SetMisalignmentCallback(func(m Misalignment) { misalignments <- m })
+	if err := g.Start(); err != nil {
+		t.Fatal(err)
+	}
+
+	var blocks = receiveGroupBlocks(t, g, 20)
+	for i, block := range blocks {
+		if i > 0 && block.Timestamp != blocks[i-1].Timestamp+groupTestBlockSize {
+			t.Errorf("block %d: expected timestamp %d, got %d", i, blocks[i-1].Timestamp+groupTestBlockSize, block.Timestamp)
+		}
+		if len(block.Blocks) != 2 {
+			t.Fatalf("block %d: expected a block of each device, got %d", i, len(block.Blocks))
+		}
+		for device, b := range block.Blocks {
+			if b.Device != device || b.Channel != 0 || len(b.Data) != groupTestBlockSize {
+				t.Errorf("block %d: expected %d samples of device %d channel 0, got %d samples of device %d channel %d",
+					i, groupTestBlockSize, device, len(b.Data), b.Device, b.Channel)
+			}
+		}
+	}
+
+	if err := g.Stop(); err != nil {
+		t.Fatal(err)
+	}
+	if len(misalignments) != 0 {
+		t.Errorf("expected no misalignment, got %+v", <-misalignments)
+	}
+}
+
+func TestGroupGap(t *testing.T) {
+	var sims = []*SimBackend{NewSimBackend(), NewSimBackend()}
+	var g = openSimGroup(t, sims...)
+	defer g.Close()
+
+	var misalignments = make(chan Misalignment, 16)
+	g.SetMisalignmentCallback(func(m Misalignment) { misalignments <- m })
+	if err := g.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer g.Stop()
+	receiveGroupBlocks(t, g, 4)
+
+	// A small gap is filled with zeros
+	sims[1].DropSamples(100)
+	var m = waitMisalignment(t, misalignments)
+	if m.Device != 1 || m.Channel != 0 || m.Samples != 100 || m.Timestamp == 0 {
+		t.Errorf("expected 100 missing samples in device 1 channel 0, got %+v", m)
+	}
+
+	var last = receiveGroupBlocks(t, g, 4)[3]
+
+	// A gap larger than the group buffer makes the group align the channels again
+	var gap = g.maxBuffered() + 1
+	sims[0].DropSamples(int(gap))
+	m = waitMisalignment(t, misalignments)
+	if m.Device != 0 || m.Channel != 0 || m.Samples != gap {
+		t.Errorf("expected %d missing samples in device 0 channel 0, got %+v", gap, m)
+	}
+
+	for _, block := range receiveGroupBlocks(t, g, 20) {
+		if block.Timestamp <= last.Timestamp {
+			t.Fatalf("expected blocks after timestamp %d once aligned again, got %d", last.Timestamp, block.Timestamp)
+		}
+	}
+}
@@ -1,14 +1,15 @@
 package limedrv
 
-import "C"
 import (
 	"bytes"
 	"encoding/binary"
-	"fmt"
-	"github.com/racerxdl/limedrv/limewrap"
+	"math"
 	"runtime"
+	"strconv"
 	"strings"
-	"unsafe"
+	"sync"
+	"sync/atomic"
+	"time"
 )
 
 func cleanString(s string) string {
@@ -19,81 +20,236 @@ type channelMessage struct {
 	channel   int
 	data      []complex64
 	timestamp uint64
+	// buffer is the pooled buffer of data. It is nil if the data is owned by a consumer of LMSChannel.Samples.
+	buffer *[]complex64
 }
 
-func streamLoop(c chan<- channelMessage, con chan bool, channel LMSChannel) {
-	var err error
-	//fmt.Fprintf(os.Stderr,"Worker Started")
-	running := true
-	sampleLength := 4
-	if channel.parent.IQFormat == FormatInt16 || channel.parent.IQFormat == FormatInt12 {
-		sampleLength = 2
+// samplePool holds the buffers used to deliver received samples, so the stream loop does not allocate in each read
+var samplePool = sync.Pool{
+	New: func() interface{} {
+		var buffer = make([]complex64, fifoSize)
+		return &buffer
+	},
+}
+
+// releaseSamples returns the buffer of the message to the pool
+func (cm channelMessage) releaseSamples() {
+	if cm.buffer != nil {
+		samplePool.Put(cm.buffer)
 	}
-	buff := make([]byte, fifoSize*sampleLength*2) // 16k IQ samples
-	zeroPointer := uintptr(unsafe.Pointer(&buff[0]))
-
-	m := limewrap.NewLms_stream_meta_t()
-	m.SetTimestamp(0)
-	m.SetFlushPartialPacket(false)
-	m.SetWaitForTimestamp(false)
-	//fmt.Fprintf(os.Stderr,"Worker Running")
-	for running {
+}
+
+func streamLoop(c chan<- channelMessage, con chan bool, channel *LMSChannel) {
+	format := channel.parent.IQFormat
+	sampleLength := 2 * iqFormatSampleSize(format)
+	buff := make([]byte, fifoSize*sampleLength) // 16k IQ samples
+
+	scale := float32(1 / IQFormatFullScale(format))
+	if channel.parent.UnscaledSamples {
+		scale = 1
+	}
+
+	failing := false // Only log a stream error once until samples are received again
+
+	m := StreamMeta{}
+	for {
 		select {
-		case _ = <-con:
-			//fmt.Fprintf(os.Stderr,"Worker Received stop", b)
-			running = false
+		case <-con:
 			return
 		default:
 		}
 
-		recvSamples := limewrap.LMS_RecvStream(channel.stream, zeroPointer, 16384, m, 100)
+		recvSamples, err := channel.parent.backend.RecvStream(channel.stream, buff, fifoSize, &m, 100)
 		if recvSamples > 0 {
-			chunk := buff[:sampleLength*recvSamples*2]
-			rbuf := bytes.NewReader(chunk)
+			failing = false
+			buffer := samplePool.Get().(*[]complex64)
 			cm := channelMessage{
 				channel:   channel.parentIndex,
-				data:      make([]complex64, recvSamples),
-				timestamp: m.GetTimestamp(),
+				data:      (*buffer)[:recvSamples],
+				timestamp: m.Timestamp,
+				buffer:    buffer,
 			}
 
-			if sampleLength == 4 {
-				// Float32
-				v := make([]float32, recvSamples)
-				err = binary.Read(rbuf, binary.LittleEndian, &v)
-				if err != nil {
-					panic(err)
-				}
-				for i := 0; i < recvSamples; i++ {
-					cm.data[i] = complex(v[i*2], v[i*2+1])
-				}
-			} else {
-				// Int16
-				//var i16a, i16b int16
-				var i16buff = make([]int16, recvSamples*2)
-				err = binary.Read(rbuf, binary.LittleEndian, &i16buff)
-				for i := 0; i < recvSamples; i++ {
-					cm.data[i] = complex(float32(i16buff[i*2])/32768, float32(i16buff[i*2+1])/32768)
-				}
+			decodeIQ(cm.data, buff[:recvSamples*sampleLength], format, scale)
+
+			delivered := channel.deliverSamples(SampleBlock{
+				Channel:   cm.channel,
+				Timestamp: cm.timestamp,
+				Data:      cm.data,
+			})
+
+			if delivered {
+				// The consumer of Samples owns the data now
+				cm.buffer = nil
 			}
 
 			c <- cm
-		} else if recvSamples == -1 {
-			fmt.Printf("Error receiving samples from channel %d\n", channel.parentIndex)
+		} else if err != nil {
+			atomic.AddUint64(&channel.streamErrors, 1)
+			if !failing {
+				channel.parent.log(LogWarning, channel.parentIndex, true, err, "failed to receive samples")
+				failing = true
+			}
+			// Do not spin while the stream is broken, for example when the board was unplugged
+			time.Sleep(time.Millisecond)
 		}
 		runtime.Gosched()
 	}
 }
 
-func createLms_range_t() limewrap.Lms_range_t {
-	return limewrap.NewLms_range_t()
+// decodeIQ converts the interleaved IQ samples in the IQFormat from the stream buffer to dst.
+// src should have len(dst) samples. Integer samples are multiplied by scale.
+func decodeIQ(dst []complex64, src []byte, format int, scale float32) {
+	switch format {
+	case FormatFloat32:
+		src = src[:len(dst)*8]
+		for i := range dst {
+			var s = src[i*8 : i*8+8]
+			dst[i] = complex(
+				math.Float32frombits(binary.LittleEndian.Uint32(s[0:4])),
+				math.Float32frombits(binary.LittleEndian.Uint32(s[4:8])),
+			)
+		}
+	default:
+		src = src[:len(dst)*4]
+		for i := range dst {
+			var s = src[i*4 : i*4+4]
+			dst[i] = complex(
+				float32(int16(binary.LittleEndian.Uint16(s[0:2])))*scale,
+				float32(int16(binary.LittleEndian.Uint16(s[2:4])))*scale,
+			)
+		}
+	}
 }
 
-func createLms_stream_t() limewrap.Lms_stream_t {
-	return limewrap.NewLms_stream_t()
+// txBlock is a block of samples queued for transmission in a TX channel
+type txBlock struct {
+	samples []complex64
+	// burst tells the samples should start at timestamp and be flushed at the end
+	burst     bool
+	timestamp uint64
 }
 
-func idev2dev(deviceinfo i_deviceinfo) DeviceInfo {
-	var deviceStr = string(deviceinfo.DeviceName[:64])
+func txStreamLoop(con chan bool, channel *LMSChannel) {
+	format := channel.parent.IQFormat
+	buff := make([]byte, fifoSize*iqFormatSampleSize(format)*2)
+	samples := make([]complex64, fifoSize)
+	var pending txBlock
+
+	sent := false     // Only count underruns after the first block was sent
+	underrun := false // Only count an underrun once until samples are available again
+	failing := false  // Only log a stream error once until samples are sent again
+	badCount := false // Only log a invalid TX callback count once until a valid one is returned
+
+	m := StreamMeta{}
+	for {
+		select {
+		case <-con:
+			return
+		default:
+		}
+
+		if len(pending.samples) == 0 {
+			select {
+			case pending = <-channel.txQueue:
+			default:
+				if cb := channel.parent.txCallback; cb != nil {
+					n := cb(samples, channel.parentIndex)
+					if n < 0 || n > len(samples) {
+						if !badCount {
+							channel.parent.log(LogError, channel.parentIndex, false, nil, "TX callback returned %d samples (maximum is %d)", n, len(samples))
+							badCount = true
+						}
+						n = int(math.Max(0, math.Min(float64(n), float64(len(samples)))))
+					} else {
+						badCount = false
+					}
+					pending = txBlock{samples: samples[:n]}
+				}
+			}
+		}
+
+		if len(pending.samples) == 0 {
+			if sent && !underrun {
+				atomic.AddUint64(&channel.txUnderruns, 1)
+				channel.parent.log(LogWarning, channel.parentIndex, false, nil, "underrun")
+				underrun = true
+			}
+			if channel.parent.txCallback != nil {
+				time.Sleep(time.Millisecond)
+				continue
+			}
+			select {
+			case <-con:
+				return
+			case pending = <-channel.txQueue:
+			}
+			continue
+		}
+
+		underrun = false
+		n := len(pending.samples)
+		if n > fifoSize {
+			n = fifoSize
+		}
+
+		encodeSamples(buff, pending.samples[:n], format)
+
+		m.WaitForTimestamp = pending.burst
+		m.Timestamp = pending.timestamp
+		m.FlushPartialPacket = pending.burst && n == len(pending.samples)
+
+		sentSamples, err := channel.parent.backend.SendStream(channel.stream, buff, n, &m, 100)
+		if err != nil {
+			atomic.AddUint64(&channel.streamErrors, 1)
+			if !failing {
+				channel.parent.log(LogWarning, channel.parentIndex, false, err, "failed to send samples")
+				failing = true
+			}
+			// Do not spin while the stream is broken, for example when the board was unplugged
+			time.Sleep(time.Millisecond)
+			continue
+		}
+
+		if sentSamples > 0 {
+			sent = true
+			failing = false
+			pending.samples = pending.samples[sentSamples:]
+			pending.timestamp += uint64(sentSamples)
+			if pending.burst && len(pending.samples) == 0 {
+				// The gap after a burst is intended, so it's not an underrun
+				sent = false
+			}
+		}
+		runtime.Gosched()
+	}
+}
+
+// encodeSamples writes the samples to buffer using the wire representation of the IQFormat
+func encodeSamples(buffer []byte, samples []complex64, format int) {
+	for i, s := range samples {
+		putIQSample(buffer, i, format, clip(float64(real(s))), clip(float64(imag(s))))
+	}
+}
+
+// clip limits v to the [-1, 1] full scale range
+func clip(v float64) float64 {
+	return math.Max(-1, math.Min(1, v))
+}
+
+// lmsInfoStrLength is the size of a lms_info_str_t, each entry of the device list of LimeSuite
+const lmsInfoStrLength = 256
+
+// maxListedDevices is the number of lms_info_str_t entries given to LMS_GetDeviceList
+const maxListedDevices = 128
+
+// entry2dev parses a lms_info_str_t of the device list, a NUL terminated string like
+// "LimeSDR-USB, media=USB 3.0, module=FX3, addr=1d50:6108, serial=0009060B00471B22"
+func entry2dev(entry []byte) DeviceInfo {
+	if n := bytes.IndexByte(entry, 0); n >= 0 {
+		entry = entry[:n]
+	}
+	var deviceStr = string(entry)
 	var z = strings.Split(deviceStr, ",")
 
 	var DeviceName string
@@ -103,9 +259,9 @@ func idev2dev(deviceinfo i_deviceinfo) DeviceInfo {
 	var Serial string
 
 	for i := 0; i < len(z); i++ {
-		var k = strings.Split(z[i], "=")
+		var k = strings.SplitN(z[i], "=", 2)
 		if len(k) == 1 {
-			DeviceName = k[0]
+			DeviceName = cleanString(k[0])
 		} else {
 			switch strings.ToLower(strings.Trim(k[0], " ")) {
 			case "media":
@@ -124,16 +280,54 @@ func idev2dev(deviceinfo i_deviceinfo) DeviceInfo {
 		}
 	}
 
+	// The serial is printed in hex in the device string
+	var BoardSerialNumber, _ = strconv.ParseUint(Serial, 16, 64)
+
 	return DeviceInfo{
-		DeviceName:          DeviceName,
-		Media:               Media,
-		Module:              Module,
-		Addr:                Addr,
-		Serial:              Serial,
-		FirmwareVersion:     cleanString(string(deviceinfo.FirmwareVersion[:16])),
-		HardwareVersion:     cleanString(string(deviceinfo.HardwareVersion[:16])),
-		GatewareVersion:     cleanString(string(deviceinfo.GatewareVersion[:16])),
-		GatewareTargetBoard: cleanString(string(deviceinfo.GatewareTargetBoard[:16])),
-		origDevInfo:         deviceinfo,
+		DeviceName:        DeviceName,
+		Media:             Media,
+		Module:            Module,
+		Addr:              Addr,
+		Serial:            Serial,
+		BoardSerialNumber: BoardSerialNumber,
+		origDevString:     deviceStr,
+	}
+}
+
+// IQFormatFullScale returns the integer value that represents the full scale (1.0) of samples in the IQFormat.
+// LimeSuite delivers FormatInt12 samples in the [-2048, 2047] range and FormatInt16 samples in the [-32768, 32767] range.
+// Received samples are divided by it unless LMSDevice.UnscaledSamples is set. FormatFloat32 samples are already normalized, so it returns 1.
+func IQFormatFullScale(format int) float64 {
+	switch format {
+	case FormatInt12:
+		return 2048
+	case FormatInt16:
+		return 32768
+	default:
+		return 1
+	}
+}
+
+// iqFormatSampleSize returns the size in bytes of each I or Q value in the specified IQFormat
+func iqFormatSampleSize(format int) int {
+	if format == FormatFloat32 {
+		return 4
+	}
+	return 2
+}
+
+// putIQSample writes the normalized IQ sample at position idx of buffer using the wire representation of the IQFormat.
+// FormatInt12 samples are stored in 16 bit words with 12 bit full scale, the same way LimeSuite delivers them.
+func putIQSample(buffer []byte, idx, format int, i, q float64) {
+	switch format {
+	case FormatFloat32:
+		binary.LittleEndian.PutUint32(buffer[idx*8:], math.Float32bits(float32(i)))
+		binary.LittleEndian.PutUint32(buffer[idx*8+4:], math.Float32bits(float32(q)))
+	case FormatInt12:
+		binary.LittleEndian.PutUint16(buffer[idx*4:], uint16(int16(math.Round(i*2047))))
+		binary.LittleEndian.PutUint16(buffer[idx*4+2:], uint16(int16(math.Round(q*2047))))
+	default:
+		binary.LittleEndian.PutUint16(buffer[idx*4:], uint16(int16(math.Round(i*32767))))
+		binary.LittleEndian.PutUint16(buffer[idx*4+2:], uint16(int16(math.Round(q*32767))))
 	}
 }
@@ -0,0 +1,204 @@
+package limedrv
+
+import (
+	"encoding/binary"
+	"math"
+	"testing"
+)
+
+// rawIQ builds a stream buffer with a single integer IQ sample
+func rawIQ(i, q int16) []byte {
+	var buffer = make([]byte, 4)
+	binary.LittleEndian.PutUint16(buffer[0:], uint16(i))
+	binary.LittleEndian.PutUint16(buffer[2:], uint16(q))
+	return buffer
+}
+
+func TestIQFormatFullScale(t *testing.T) {
+	var tests = []struct {
+		format int
+		want   float64
+	}{
+		{FormatFloat32, 1},
+		{FormatInt12, 2048},
+		{FormatInt16, 32768},
+	}
+
+	for _, tt := range tests {
+		if got := IQFormatFullScale(tt.format); got != tt.want {
+			t.Errorf("IQFormatFullScale(%d) = %v, want %v", tt.format, got, tt.want)
+		}
+	}
+}
+
+func TestDecodeIQ(t *testing.T) {
+	var f32 = make([]byte, 8)
+	binary.LittleEndian.PutUint32(f32[0:], math.Float32bits(1))
+	binary.LittleEndian.PutUint32(f32[4:], math.Float32bits(-0.25))
+
+	var tests = []struct {
+		name     string
+		format   int
+		unscaled bool
+		src      []byte
+		want     complex64
+	}{
+		{"Int12 full scale", FormatInt12, false, rawIQ(-2048, 1024), complex(-1, 0.5)},
+		{"Int12 positive limit", FormatInt12, false, rawIQ(2047, 0), complex(2047.0/2048, 0)},
+		{"Int16 full scale", FormatInt16, false, rawIQ(-32768, 16384), complex(-1, 0.5)},
+		{"Int16 positive limit", FormatInt16, false, rawIQ(32767, 0), complex(32767.0/32768, 0)},
+		{"Float32", FormatFloat32, false, f32, complex(1, -0.25)},
+		{"Int12 unscaled", FormatInt12, true, rawIQ(-2048, 2047), complex(-2048, 2047)},
+		{"Int16 unscaled", FormatInt16, true, rawIQ(-32768, 32767), complex(-32768, 32767)},
+		{"Float32 unscaled", FormatFloat32, true, f32, complex(1, -0.25)},
+	}
+
+	for _, tt := range tests {
+		var scale = float32(1 / IQFormatFullScale(tt.format))
+		if tt.unscaled {
+			scale = 1
+		}
+
+		var dst = make([]complex64, 1)
+		decodeIQ(dst, tt.src, tt.format, scale)
+		if dst[0] != tt.want {
+			t.Errorf("%s: got %v, want %v", tt.name, dst[0], tt.want)
+		}
+	}
+}
+
+func TestEncodeDecodeIQ(t *testing.T) {
+	var samples = []complex64{complex(0, 0), complex(0.5, -0.5), complex(-1, 1), complex(0.123, -0.987)}
+
+	for _, format := range []int{FormatFloat32, FormatInt12, FormatInt16} {
+		var buffer = make([]byte, len(samples)*2*iqFormatSampleSize(format))
+		encodeSamples(buffer, samples, format)
+
+		var got = make([]complex64, len(samples))
+		decodeIQ(got, buffer, format, float32(1/IQFormatFullScale(format)))
+
+		// One LSB of error is expected in integer formats
+		var tolerance = 2 / IQFormatFullScale(format)
+		if format == FormatFloat32 {
+			tolerance = 0
+		}
+
+		for i := range samples {
+			if math.Abs(float64(real(got[i]-samples[i]))) > tolerance || math.Abs(float64(imag(got[i]-samples[i]))) > tolerance {
+				t.Errorf("format %d sample %d: got %v, want %v", format, i, got[i], samples[i])
+			}
+		}
+	}
+}
+
+func benchmarkDecodeIQ(b *testing.B, format int) {
+	var src = make([]byte, fifoSize*2*iqFormatSampleSize(format))
+	for i := 0; i < fifoSize; i++ {
+		putIQSample(src, i, format, 0.5, -0.5)
+	}
+	var dst = make([]complex64, fifoSize)
+
+	b.SetBytes(int64(len(src)))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		decodeIQ(dst, src, format, float32(1/IQFormatFullScale(format)))
+	}
+}
+
+func BenchmarkDecodeIQInt16(b *testing.B) {
+	benchmarkDecodeIQ(b, FormatInt16)
+}
+
+func BenchmarkDecodeIQInt12(b *testing.B) {
+	benchmarkDecodeIQ(b, FormatInt12)
+}
+
+func BenchmarkDecodeIQFloat32(b *testing.B) {
+	benchmarkDecodeIQ(b, FormatFloat32)
+}
+
+// benchmarkStreamLoop measures the whole receive path, from the backend buffer to the device loop
+func benchmarkStreamLoop(b *testing.B, format int) {
+	var d = &LMSDevice{IQFormat: format}
+	var ch = &LMSChannel{IsRX: true, parent: d, stream: 1}
+	d.backend = benchmarkBackend{NewSimBackend()}
+
+	var data = make(chan channelMessage)
+	var con = make(chan bool)
+	go streamLoop(data, con, ch)
+
+	b.SetBytes(int64(fifoSize * 2 * iqFormatSampleSize(format)))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		msg := <-data
+		msg.releaseSamples()
+	}
+	b.StopTimer()
+
+	for stopped := false; !stopped; {
+		select {
+		case con <- true:
+			stopped = true
+		case msg := <-data:
+			msg.releaseSamples()
+		}
+	}
+}
+
+// benchmarkBackend is a Backend that returns full blocks without pacing or synthesizing samples
+type benchmarkBackend struct {
+	*SimBackend
+}
+
+func (benchmarkBackend) RecvStream(stream uintptr, buffer []byte, sampleCount int, meta *StreamMeta, timeoutMs uint) (int, error) {
+	meta.Timestamp += uint64(sampleCount)
+	return sampleCount, nil
+}
+
+func BenchmarkStreamLoopInt16(b *testing.B) {
+	benchmarkStreamLoop(b, FormatInt16)
+}
+
+func BenchmarkStreamLoopInt12(b *testing.B) {
+	benchmarkStreamLoop(b, FormatInt12)
+}
+
+func BenchmarkStreamLoopFloat32(b *testing.B) {
+	benchmarkStreamLoop(b, FormatFloat32)
+}
+
+func TestEntry2dev(t *testing.T) {
+	// Entries of the LMS_GetDeviceList list are lms_info_str_t, NUL terminated strings in 256 bytes
+	var tests = []struct {
+		str                               string
+		name, media, module, addr, serial string
+		boardSerial                       uint64
+	}{
+		{"LimeSDR-USB, media=USB 3.0, module=FX3, addr=1d50:6108, serial=0009060B00471B22",
+			"LimeSDR-USB", "USB 3.0", "FX3", "1d50:6108", "0009060B00471B22", 0x0009060B00471B22},
+		{"LimeSDR Mini, media=USB 3.0, module=FT601, addr=24607:1027, serial=1D3AC7A1E3B5F4",
+			"LimeSDR Mini", "USB 3.0", "FT601", "24607:1027", "1D3AC7A1E3B5F4", 0x1D3AC7A1E3B5F4},
+		{"LimeSDR-USB, media=USB 2.0, module=STREAM, addr=1d50:6108, serial=0009060B00471B22, index=1",
+			"LimeSDR-USB", "USB 2.0", "STREAM", "1d50:6108", "0009060B00471B22", 0x0009060B00471B22},
+	}
+
+	for _, tt := range tests {
+		var entry [lmsInfoStrLength]byte
+		copy(entry[:], tt.str)
+		// Leftovers of a previous longer entry after the terminator should be ignored
+		copy(entry[len(tt.str)+1:], "garbage")
+
+		var dev = entry2dev(entry[:])
+		if dev.DeviceName != tt.name || dev.Media != tt.media || dev.Module != tt.module || dev.Addr != tt.addr {
+			t.Errorf("%q: wrong device string fields: %+v", tt.str, dev)
+		}
+		if dev.Serial != tt.serial || dev.BoardSerialNumber != tt.boardSerial {
+			t.Errorf("%q: expected serial %s (%X), got %s (%X)", tt.str, tt.serial, tt.boardSerial, dev.Serial, dev.BoardSerialNumber)
+		}
+		if dev.origDevString != tt.str {
+			t.Errorf("expected the device string %q to be kept for LMS_Open, got %q", tt.str, dev.origDevString)
+		}
+	}
+}
@@ -0,0 +1,205 @@
+package limedrv
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+)
+
+// WireFormat is the representation of interleaved IQ samples used by the io.Reader and io.Writer of LMSChannel.
+// All formats are little endian.
+type WireFormat int
+
+const (
+	// WireCF32 represents each I and Q value as a 32 bit float in the [-1, 1] range
+	WireCF32 WireFormat = iota
+	// WireCS16 represents each I and Q value as a signed 16 bit integer
+	WireCS16
+	// WireCS8 represents each I and Q value as a signed 8 bit integer
+	WireCS8
+	// WireCU8 represents each I and Q value as a unsigned 8 bit integer centered at 127.5 (Same as rtl_sdr)
+	WireCU8
+)
+
+var wireFormatNames = map[WireFormat]string{
+	WireCF32: "cf32",
+	WireCS16: "cs16",
+	WireCS8:  "cs8",
+	WireCU8:  "cu8",
+}
+
+// String returns the name of the format, for example cs16
+func (f WireFormat) String() string {
+	if name, ok := wireFormatNames[f]; ok {
+		return name
+	}
+	return fmt.Sprintf("WireFormat(%d)", int(f))
+}
+
+// SampleSize returns the size in bytes of a IQ sample in this format
+func (f WireFormat) SampleSize() int {
+	switch f {
+	case WireCF32:
+		return 8
+	case WireCS16:
+		return 4
+	default:
+		return 2
+	}
+}
+
+// ParseWireFormat returns the WireFormat by its name (cf32, cs16, cs8 or cu8)
+func ParseWireFormat(name string) (WireFormat, error) {
+	for f, n := range wireFormatNames {
+		if strings.EqualFold(n, name) {
+			return f, nil
+		}
+	}
+	return 0, fmt.Errorf("limedrv: unknown wire format %q: %w", name, ErrOutOfRange)
+}
+
+// encodeWire writes the samples to buffer in the wire format. buffer should have at least len(samples) * format.SampleSize() bytes.
+func encodeWire(buffer []byte, samples []complex64, format WireFormat) {
+	for i, s := range samples {
+		var iv, qv = clip(float64(real(s))), clip(float64(imag(s)))
+		switch format {
+		case WireCF32:
+			binary.LittleEndian.PutUint32(buffer[i*8:], math.Float32bits(float32(iv)))
+			binary.LittleEndian.PutUint32(buffer[i*8+4:], math.Float32bits(float32(qv)))
+		case WireCS16:
+			binary.LittleEndian.PutUint16(buffer[i*4:], uint16(int16(math.Round(iv*32767))))
+			binary.LittleEndian.PutUint16(buffer[i*4+2:], uint16(int16(math.Round(qv*32767))))
+		case WireCS8:
+			buffer[i*2] = byte(int8(math.Round(iv * 127)))
+			buffer[i*2+1] = byte(int8(math.Round(qv * 127)))
+		case WireCU8:
+			buffer[i*2] = byte(math.Round(iv*127.5 + 127.5))
+			buffer[i*2+1] = byte(math.Round(qv*127.5 + 127.5))
+		}
+	}
+}
+
+// decodeWire reads len(samples) samples from buffer in the wire format
+func decodeWire(buffer []byte, samples []complex64, format WireFormat) {
+	for i := range samples {
+		var iv, qv float32
+		switch format {
+		case WireCF32:
+			iv = math.Float32frombits(binary.LittleEndian.Uint32(buffer[i*8:]))
+			qv = math.Float32frombits(binary.LittleEndian.Uint32(buffer[i*8+4:]))
+		case WireCS16:
+			iv = float32(int16(binary.LittleEndian.Uint16(buffer[i*4:]))) / 32767
+			qv = float32(int16(binary.LittleEndian.Uint16(buffer[i*4+2:]))) / 32767
+		case WireCS8:
+			iv = float32(int8(buffer[i*2])) / 127
+			qv = float32(int8(buffer[i*2+1])) / 127
+		case WireCU8:
+			iv = (float32(buffer[i*2]) - 127.5) / 127.5
+			qv = (float32(buffer[i*2+1]) - 127.5) / 127.5
+		}
+		samples[i] = complex(iv, qv)
+	}
+}
+
+// channelReader is the io.Reader of a RX channel
+type channelReader struct {
+	channel *LMSChannel
+	format  WireFormat
+	pending []byte
+	eof     bool
+}
+
+func (r *channelReader) Read(p []byte) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+
+	if len(r.pending) == 0 {
+		if r.eof {
+			return 0, io.EOF
+		}
+
+		// The Go channel is looked up in every read, since it is replaced after the device stops or by SetSamplesDepth
+		block, ok := <-r.channel.Samples()
+		if !ok {
+			r.eof = true
+			return 0, io.EOF
+		}
+
+		var size = len(block.Data) * r.format.SampleSize()
+		if cap(r.pending) < size {
+			r.pending = make([]byte, size)
+		}
+		r.pending = r.pending[:size]
+		encodeWire(r.pending, block.Data, r.format)
+	}
+
+	n := copy(p, r.pending)
+	r.pending = r.pending[n:]
+	return n, nil
+}
+
+// channelWriter is the io.Writer of a TX channel
+type channelWriter struct {
+	channel *LMSChannel
+	format  WireFormat
+	partial []byte
+}
+
+func (w *channelWriter) Write(p []byte) (int, error) {
+	var data = p
+	if len(w.partial) > 0 {
+		data = append(w.partial, p...)
+	}
+
+	var sampleSize = w.format.SampleSize()
+	var count = len(data) / sampleSize
+	if count > 0 {
+		samples := make([]complex64, count)
+		decodeWire(data, samples, w.format)
+		if err := w.channel.WriteSamples(samples); err != nil {
+			return 0, err
+		}
+	}
+
+	// Keep an incomplete sample for the next write
+	w.partial = append(w.partial[:0], data[count*sampleSize:]...)
+	return len(p), nil
+}
+
+// Reader returns a io.Reader that yields the samples received by this RX channel as interleaved IQ in the specified wire format.
+// It reads from the same Go channel returned by Samples, so both should not be used at the same time.
+// Reads block until the device is started, and return io.EOF once the device stops or is closed.
+func (c *LMSChannel) Reader(format WireFormat) (io.Reader, error) {
+	if !c.IsRX {
+		return nil, c.parent.channelError("create reader", c.parentIndex, c.IsRX, nil, ErrWrongDirection)
+	}
+
+	if _, ok := wireFormatNames[format]; !ok {
+		return nil, c.parent.channelError(fmt.Sprintf("create reader with %s", format), c.parentIndex, c.IsRX, nil, ErrOutOfRange)
+	}
+
+	return &channelReader{
+		channel: c,
+		format:  format,
+	}, nil
+}
+
+// Writer returns a io.Writer that transmits interleaved IQ samples in the specified wire format through this TX channel.
+// Samples are queued with WriteSamples, so writes block while the TX queue is full.
+func (c *LMSChannel) Writer(format WireFormat) (io.Writer, error) {
+	if c.IsRX {
+		return nil, c.parent.channelError("create writer", c.parentIndex, c.IsRX, nil, ErrWrongDirection)
+	}
+
+	if _, ok := wireFormatNames[format]; !ok {
+		return nil, c.parent.channelError(fmt.Sprintf("create writer with %s", format), c.parentIndex, c.IsRX, nil, ErrOutOfRange)
+	}
+
+	return &channelWriter{
+		channel: c,
+		format:  format,
+	}, nil
+}
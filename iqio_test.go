@@ -0,0 +1,94 @@
+package limedrv
+
+import (
+	"io"
+	"io/ioutil"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestWireRoundTrip(t *testing.T) {
+	var samples = []complex64{
+		complex(0, 0),
+		complex(1, -1),
+		complex(0.5, -0.25),
+		complex(-0.999, 0.001),
+		complex(0.1234, 0.5678),
+	}
+
+	// Maximum error is half of the quantization step of each format
+	var tolerances = map[WireFormat]float64{
+		WireCF32: 0,
+		WireCS16: 0.5 / 32767,
+		WireCS8:  0.5 / 127,
+		WireCU8:  0.5 / 127.5,
+	}
+
+	for format, tolerance := range tolerances {
+		var buffer = make([]byte, len(samples)*format.SampleSize())
+		encodeWire(buffer, samples, format)
+
+		var decoded = make([]complex64, len(samples))
+		decodeWire(buffer, decoded, format)
+
+		for i, s := range samples {
+			var errI = math.Abs(float64(real(decoded[i]) - real(s)))
+			var errQ = math.Abs(float64(imag(decoded[i]) - imag(s)))
+			if errI > tolerance+1e-7 || errQ > tolerance+1e-7 {
+				t.Errorf("%s: sample %d was %v, decoded as %v", format, i, s, decoded[i])
+			}
+		}
+	}
+}
+
+func TestWireClip(t *testing.T) {
+	var samples = []complex64{complex(2, -3)}
+	for _, format := range []WireFormat{WireCF32, WireCS16, WireCS8, WireCU8} {
+		var buffer = make([]byte, format.SampleSize())
+		encodeWire(buffer, samples, format)
+
+		var decoded = make([]complex64, 1)
+		decodeWire(buffer, decoded, format)
+		if real(decoded[0]) < 0.99 || real(decoded[0]) > 1 || imag(decoded[0]) > -0.99 || imag(decoded[0]) < -1 {
+			t.Errorf("%s: expected samples out of range to be clipped to full scale, got %v", format, decoded[0])
+		}
+	}
+}
+
+func TestReaderEOF(t *testing.T) {
+	var d = openSim(t, 100e6, SimSignal{Type: SimNoise, Amplitude: 0.1})
+	defer d.Close()
+
+	r, err := d.RXChannels[0].Reader(WireCS16)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Start(); err != nil {
+		t.Fatal(err)
+	}
+
+	var done = make(chan error)
+	go func() {
+		_, err := io.Copy(ioutil.Discard, r)
+		done <- err
+	}()
+
+	time.Sleep(20 * time.Millisecond)
+	if err := d.Stop(); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("expected io.Copy to finish without error, got %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("io.Copy did not finish after Stop")
+	}
+
+	if n, err := r.Read(make([]byte, 4)); n != 0 || err != io.EOF {
+		t.Errorf("expected io.EOF after the end of the stream, got %d, %v", n, err)
+	}
+}
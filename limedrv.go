@@ -4,101 +4,148 @@
 package limedrv
 
 import (
-	"bytes"
-	"encoding/binary"
-	"fmt"
-	"github.com/racerxdl/limedrv/limewrap"
 	"runtime"
-	"unsafe"
 )
 
-type i_deviceinfo struct {
-	DeviceName          [64]byte
-	FirmwareVersion     [16]byte
-	HardwareVersion     [16]byte
-	ProtocolVersion     [16]byte
-	BoardSerialNumber   uint64
-	GatewareVersion     [16]byte
-	GatewareTargetBoard [32]byte
+// DeviceInfo is a struct with driver information required to open a connection.
+// It has what LimeSuite lists for the device; the versions and the board are in the BoardInfo returned by LMSDevice.Info.
+type DeviceInfo struct {
+	DeviceName        string
+	Media             string
+	Module            string
+	Addr              string
+	Serial            string
+	BoardSerialNumber uint64
+	// origDevString is the device string listed by LimeSuite, used to open the device
+	origDevString string
+	backend       Backend
 }
 
-// DeviceInfo is a struct with driver information required to open a connection
-type DeviceInfo struct {
-	DeviceName          string
-	Media               string
-	Module              string
-	Addr                string
-	Serial              string
-	ProtocolVersion     string
-	FirmwareVersion     string
-	HardwareVersion     string
-	GatewareVersion     string
-	GatewareTargetBoard string
-	origDevInfo         i_deviceinfo
+var registeredBackends []Backend
+
+// RegisterBackend adds a backend to the list of backends used by GetDevices.
+// For example, registering a SimBackend makes a simulated device available to any code that uses GetDevices.
+func RegisterBackend(backend Backend) {
+	registeredBackends = append(registeredBackends, backend)
+	applyLogger(backend)
 }
 
-func (d *i_deviceinfo) toOrigDevString() string {
-	var buf bytes.Buffer
-	err := binary.Write(&buf, binary.LittleEndian, d)
-	if err != nil {
-		panic(err)
+// allBackends returns the default backend, if there is one, and all registered backends
+func allBackends() []Backend {
+	var backends = registeredBackends
+	if defaultBackend != nil {
+		backends = append([]Backend{defaultBackend}, backends...)
 	}
-
-	return buf.String()
+	return backends
 }
 
-// GetDevices return an array of available devices in the LMS7 driver.
+// GetDevices return an array of available devices in the LMS7 driver and in all registered backends.
 func GetDevices() []DeviceInfo {
+	var ret = make([]DeviceInfo, 0)
+
+	for _, backend := range allBackends() {
+		devices, err := GetDevicesFrom(backend)
+		if err != nil {
+			continue
+		}
+		ret = append(ret, devices...)
+	}
+
+	return ret
+}
+
+// GetDevicesFrom return an array of available devices in the specified backend.
+// Devices returned by this function will be opened using the same backend.
+func GetDevicesFrom(backend Backend) ([]DeviceInfo, error) {
 	runtime.LockOSThread()
 	defer runtime.UnlockOSThread()
-	devCount := limewrap.LMS_GetDeviceList(nil)
-	ret := make([]DeviceInfo, devCount)
-
-	if devCount > 0 {
-		var z [128]i_deviceinfo
-		t := (*string)(unsafe.Pointer(&z))
-		limewrap.LMS_GetDeviceList(t)
-		for i := 0; i < devCount; i++ {
-			ret[i] = idev2dev(z[i])
+	devices, err := backend.GetDeviceList()
+	if err != nil {
+		return nil, &LMSError{
+			Op:      "get device list",
+			Channel: -1,
+			Message: err.Error(),
+			Err:     err,
 		}
 	}
 
-	return ret
+	for i := range devices {
+		devices[i].backend = backend
+	}
+
+	return devices, nil
 }
 
 // Open opens a device specified by a DeviceInfo instance and returns a reference to LMSDevice
-func Open(device DeviceInfo) *LMSDevice {
+func Open(device DeviceInfo) (*LMSDevice, error) {
 	var ret = LMSDevice{
 		DeviceInfo:  device,
 		IQFormat:    FormatInt16,
 		controlChan: make(chan bool),
+		backend:     device.backend,
+	}
+
+	ret.Advanced = LMSDeviceAdvanced{
+		parent: &ret,
 	}
 
-	ret.Advanced = LMSDeviceAdvanced{}
+	ret.GPIO = LMSGPIO{
+		parent: &ret,
+	}
+
+	if ret.backend == nil {
+		ret.backend = defaultBackend
+	}
 
-	var origString = device.origDevInfo.toOrigDevString()
+	if ret.backend == nil {
+		return nil, ret.deviceError("open", nil, ErrDeviceNotFound)
+	}
 
-	ptr := uintptr(0)
+	if device.backend != nil && device.backend != defaultBackend {
+		// The backend may not be registered when the device was listed with GetDevicesFrom
+		applyLogger(ret.backend)
+	}
 
 	runtime.LockOSThread()
-	v := limewrap.LMS_Open(&ptr, origString, 0)
+	dev, err := ret.backend.Open(device)
 	runtime.UnlockOSThread()
-	ret.dev = ptr
 
-	if v != 0 {
-		panic(fmt.Sprintf("Failed to open %s at %s.", device.DeviceName, device.Media))
+	if err != nil {
+		err = ret.deviceError("open", err, ErrDeviceNotFound)
+		ret.log(LogError, -1, false, err, "failed to open device")
+		return nil, err
 	}
 
-	ret.init()
+	ret.dev = dev
 
-	return &ret
+	if err := ret.init(); err != nil {
+		ret.log(LogError, -1, false, err, "failed to initialize device")
+		ret.backend.Close(ret.dev)
+		ret.dev = 0
+		return nil, err
+	}
+
+	trackDevice(&ret)
+	ret.log(LogInfo, -1, false, nil, "opened device at %s with %d RX and %d TX channels", ret.DeviceInfo.Media, len(ret.RXChannels), len(ret.TXChannels))
+	return &ret, nil
 }
 
 // Close closes a LMSDevice. This makes the LMSDevice instance useless.
-func Close(device *LMSDevice) {
-	if limewrap.LMS_Close(device.dev) != 0 {
-		panic(fmt.Sprintf("Failed to close %s at %s.", device.DeviceInfo.DeviceName, device.DeviceInfo.Media))
-	} else {
-		device.dev = 0
+func Close(device *LMSDevice) error {
+	// WatchDevices should not check the device while or after its handle is released
+	untrackDevice(device)
+	device.devMtx.Lock()
+	if err := device.backend.Close(device.dev); err != nil {
+		device.devMtx.Unlock()
+		trackDevice(device)
+		return device.deviceError("close", err, nil)
+	}
+	device.dev = 0
+	device.devMtx.Unlock()
+
+	for _, ch := range device.RXChannels {
+		ch.closeSamples()
 	}
+	device.log(LogInfo, -1, false, nil, "closed device")
+	return nil
 }
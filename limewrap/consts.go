@@ -2,3 +2,6 @@ package limewrap
 
 const LmsChTx = true
 const LmsChRx = false
+
+// LMS_LOG_CRITICAL is the LimeSuite log level of unrecoverable errors, that is not in the generated wrapper
+const LMS_LOG_CRITICAL int = 0
@@ -0,0 +1,43 @@
+package limewrap
+
+/*
+void limewrapRegisterLogHandler(int enabled);
+*/
+import "C"
+
+import (
+	"sync"
+)
+
+// LogHandler receives the level (LMS_LOG_CRITICAL to LMS_LOG_DEBUG) and the message of each LimeSuite log line
+type LogHandler func(level int, message string)
+
+var (
+	logMtx     sync.RWMutex
+	logHandler LogHandler
+)
+
+//export limewrapLogMessage
+func limewrapLogMessage(level C.int, message *C.char) {
+	logMtx.RLock()
+	var handler = logHandler
+	logMtx.RUnlock()
+
+	if handler != nil {
+		handler(int(level), C.GoString(message))
+	}
+}
+
+// LMS_RegisterGoLogHandler calls LMS_RegisterLogHandler with a Go handler, which the SWIG wrapper cannot bridge.
+// A nil handler restores the default LimeSuite handler, that writes to stderr.
+func LMS_RegisterGoLogHandler(handler LogHandler) {
+	logMtx.Lock()
+	logHandler = handler
+	logMtx.Unlock()
+
+	var enabled = 0
+	if handler != nil {
+		enabled = 1
+	}
+	C.limewrapRegisterLogHandler(C.int(enabled))
+}
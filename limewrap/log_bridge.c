@@ -0,0 +1,12 @@
+#include <lime/LimeSuite.h>
+#include "_cgo_export.h"
+
+static void limewrapLogCallback(int lvl, const char *msg)
+{
+    limewrapLogMessage(lvl, (char *)msg);
+}
+
+void limewrapRegisterLogHandler(int enabled)
+{
+    LMS_RegisterLogHandler(enabled ? limewrapLogCallback : NULL);
+}
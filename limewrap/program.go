@@ -0,0 +1,51 @@
+package limewrap
+
+/*
+#include <stdbool.h>
+#include <stdint.h>
+#include <stdlib.h>
+
+int limewrapProgram(uintptr_t device, const char *data, size_t size, const char *mode, bool progress);
+*/
+import "C"
+
+import (
+	"sync"
+	"unsafe"
+)
+
+// ProgramProgress receives the bytes sent, the total bytes and the status message of LMS_Program.
+// Returning true aborts the programming.
+type ProgramProgress func(sent, total int, message string) bool
+
+var (
+	programMtx      sync.Mutex
+	programProgress ProgramProgress
+)
+
+//export limewrapProgramProgress
+func limewrapProgramProgress(sent, total C.int, message *C.char) C.int {
+	if programProgress != nil && programProgress(int(sent), int(total), C.GoString(message)) {
+		return 1
+	}
+	return 0
+}
+
+// LMS_ProgramWithProgress calls LMS_Program with a Go progress callback, which the SWIG wrapper cannot bridge.
+// The LimeSuite callback has no user data, so only one device is programmed at a time.
+func LMS_ProgramWithProgress(device uintptr, data []byte, mode string, progress ProgramProgress) int {
+	programMtx.Lock()
+	defer programMtx.Unlock()
+
+	programProgress = progress
+	defer func() {
+		programProgress = nil
+	}()
+
+	var cMode = C.CString(mode)
+	defer C.free(unsafe.Pointer(cMode))
+	var cData = C.CBytes(data)
+	defer C.free(cData)
+
+	return int(C.limewrapProgram(C.uintptr_t(device), (*C.char)(cData), C.size_t(len(data)), cMode, C.bool(progress != nil)))
+}
@@ -0,0 +1,14 @@
+#include <stdbool.h>
+#include <stdint.h>
+#include <lime/LimeSuite.h>
+#include "_cgo_export.h"
+
+static bool limewrapProgramCallback(int bsent, int btotal, const char *progressMsg)
+{
+    return limewrapProgramProgress(bsent, btotal, (char *)progressMsg) != 0;
+}
+
+int limewrapProgram(uintptr_t device, const char *data, size_t size, const char *mode, bool progress)
+{
+    return LMS_Program((lms_device_t *)device, data, size, mode, progress ? limewrapProgramCallback : NULL);
+}
@@ -0,0 +1,33 @@
+package limewrap
+
+/*
+#include <stdint.h>
+#include <stdlib.h>
+#include <lime/LimeSuite.h>
+
+static int limewrapUploadWFM(uintptr_t device, const void **samples, uint8_t chCount, size_t sampleCount, int format)
+{
+    return LMS_UploadWFM((lms_device_t *)device, samples, chCount, sampleCount, format);
+}
+*/
+import "C"
+
+import (
+	"unsafe"
+)
+
+// LMS_UploadWFMBuffers calls LMS_UploadWFM with one buffer of samples per channel.
+// The buffers are copied to C memory, since cgo does not allow passing a Go array of Go pointers.
+func LMS_UploadWFMBuffers(device uintptr, buffers [][]byte, sampleCount int, format int) int {
+	var count = len(buffers)
+	var array = C.malloc(C.size_t(count) * C.size_t(unsafe.Sizeof(unsafe.Pointer(nil))))
+	defer C.free(array)
+
+	var pointers = (*[256]unsafe.Pointer)(array)[:count:count]
+	for i, buffer := range buffers {
+		pointers[i] = C.CBytes(buffer)
+		defer C.free(pointers[i])
+	}
+
+	return int(C.limewrapUploadWFM(C.uintptr_t(device), (*unsafe.Pointer)(array), C.uint8_t(count), C.size_t(sampleCount), C.int(format)))
+}
@@ -17,8 +17,8 @@ type LMSAntenna struct {
 }
 
 // Set sets this antenna port as the default in parent channel
-func (a *LMSAntenna) Set() {
-	a.parent.parent.SetAntenna(a.index, a.parent.parentIndex, a.parent.IsRX)
+func (a *LMSAntenna) Set() error {
+	return a.parent.parent.SetAntenna(a.index, a.parent.parentIndex, a.parent.IsRX)
 }
 
 // String returns a representation of the antenna port data
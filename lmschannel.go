@@ -2,9 +2,20 @@ package limedrv
 
 import (
 	"fmt"
-	"github.com/racerxdl/limedrv/limewrap"
+	"sync"
+	"sync/atomic"
 )
 
+// SampleBlock is a block of samples received from a RX channel
+type SampleBlock struct {
+	// Channel is the channel number the samples were received
+	Channel int
+	// Timestamp is the hardware sample counter of the first sample in Data
+	Timestamp uint64
+	// Data is the received samples. It is shared with the callback set by LMSDevice.SetCallback, so it should not be modified.
+	Data []complex64
+}
+
 // LMSChannel is the struct that represents a Channel from a LMSDevice.
 // It can be either a RX or TX Channel, defined by the field IsRX.
 // It also contains the list of available antenna ports.
@@ -14,111 +25,125 @@ type LMSChannel struct {
 
 	parent                  *LMSDevice
 	parentIndex             int
-	stream                  limewrap.Lms_stream_t
+	enabled                 bool
+	frequency               float64
+	ncoIndex                int // -1 if the NCO is disabled
+	ncoDownConvert          bool
+	ncoPhaseMode            bool
+	lpfBandwidth            float64
+	calibration             channelCalibration
+	stream                  uintptr
 	currentDigitalBandwidth float64
 	digitalFilterEnabled    bool
 	advancedFiltering       bool
+	txQueue                 chan txBlock
+	txUnderruns             uint64
+	streamErrors            uint64
+	statsMtx                sync.Mutex
+	stats                   StreamStats
+	samplesMtx              sync.Mutex
+	samples                 chan SampleBlock
+	samplesDepth            int
+	droppedBlocks           uint64
 }
 
 // Enable enables this channel from the read / write callback
-func (c *LMSChannel) Enable() *LMSChannel {
-	c.parent.EnableChannel(c.parentIndex, c.IsRX)
-	return c
+func (c *LMSChannel) Enable() error {
+	return c.parent.EnableChannel(c.parentIndex, c.IsRX)
 }
 
 // Disable disables this channel from the read / write callback
-func (c *LMSChannel) Disable() *LMSChannel {
-	c.parent.DisableChannel(c.parentIndex, c.IsRX)
-	return c
+func (c *LMSChannel) Disable() error {
+	return c.parent.DisableChannel(c.parentIndex, c.IsRX)
 }
 
 // SetGainDB sets this channel gain in decibels
-func (c *LMSChannel) SetGainDB(gain uint) *LMSChannel {
-	c.parent.SetGainDB(c.parentIndex, c.IsRX, gain)
-	return c
+func (c *LMSChannel) SetGainDB(gain uint) error {
+	return c.parent.SetGainDB(c.parentIndex, c.IsRX, gain)
 }
 
 // SetGainNormalized sets the channel normalized gain. [0-1]
-func (c *LMSChannel) SetGainNormalized(gain float64) *LMSChannel {
-	c.parent.SetGainNormalized(c.parentIndex, c.IsRX, gain)
-	return c
+func (c *LMSChannel) SetGainNormalized(gain float64) error {
+	return c.parent.SetGainNormalized(c.parentIndex, c.IsRX, gain)
 }
 
 // GetGainDB returns the channel current gain in decibels
-func (c *LMSChannel) GetGainDB() uint {
+func (c *LMSChannel) GetGainDB() (uint, error) {
 	return c.parent.GetGainDB(c.parentIndex, c.IsRX)
 }
 
 // GetGainNormalized returns the channel current normalized gain. [0-1]
-func (c *LMSChannel) GetGainNormalized() float64 {
+func (c *LMSChannel) GetGainNormalized() (float64, error) {
 	return c.parent.GetGainNormalized(c.parentIndex, c.IsRX)
 }
 
 // SetLPF sets the Analog Low Pass filter bandwidth for the current channel.
-func (c *LMSChannel) SetLPF(bandwidth float64) *LMSChannel {
-	c.parent.SetLPF(c.parentIndex, c.IsRX, bandwidth)
-	return c
+func (c *LMSChannel) SetLPF(bandwidth float64) error {
+	return c.parent.SetLPF(c.parentIndex, c.IsRX, bandwidth)
 }
 
 // GetLPF gets the current Analog Low Pass filter bandwidth for the current channel.
-func (c *LMSChannel) GetLPF() float64 {
+func (c *LMSChannel) GetLPF() (float64, error) {
 	return c.parent.GetLPF(c.parentIndex, c.IsRX)
 }
 
 // EnableLPF enables the Analog Low Pass filter for the current channel.
-func (c *LMSChannel) EnableLPF() *LMSChannel {
-	c.parent.EnableLPF(c.parentIndex, c.IsRX)
-	return c
+func (c *LMSChannel) EnableLPF() error {
+	return c.parent.EnableLPF(c.parentIndex, c.IsRX)
 }
 
 // DisableLPF disables the Analog Low Pass filter for the current channel.
-func (c *LMSChannel) DisableLPF() *LMSChannel {
-	c.parent.EnableLPF(c.parentIndex, c.IsRX)
-	return c
+func (c *LMSChannel) DisableLPF() error {
+	return c.parent.DisableLPF(c.parentIndex, c.IsRX)
 }
 
 // SetDigitalLPF sets the current channel digital filter (GFIR) to low pass with specified bandwidth.
-func (c *LMSChannel) SetDigitalLPF(bandwidth float64) *LMSChannel {
-	c.parent.SetDigitalFilter(c.parentIndex, c.IsRX, bandwidth)
-	return c
+func (c *LMSChannel) SetDigitalLPF(bandwidth float64) error {
+	return c.parent.SetDigitalFilter(c.parentIndex, c.IsRX, bandwidth)
 }
 
 // EnableDigitalLPF enables current channel digital filter (GFIR)
-func (c *LMSChannel) EnableDigitalLPF() *LMSChannel {
-	c.parent.EnableDigitalFilter(c.parentIndex, c.IsRX)
-	return c
+func (c *LMSChannel) EnableDigitalLPF() error {
+	return c.parent.EnableDigitalFilter(c.parentIndex, c.IsRX)
 }
 
 // DisableDigitalLPF disables current channel digital filter (GFIR)
-func (c *LMSChannel) DisableDigitalLPF() *LMSChannel {
-	c.parent.DisableDigitalFilter(c.parentIndex, c.IsRX)
-	return c
+func (c *LMSChannel) DisableDigitalLPF() error {
+	return c.parent.DisableDigitalFilter(c.parentIndex, c.IsRX)
 }
 
 // SetAntenna sets the current channel antenna port
-func (c *LMSChannel) SetAntenna(idx int) *LMSChannel {
-	c.parent.SetAntenna(idx, c.parentIndex, c.IsRX)
-	return c
+func (c *LMSChannel) SetAntenna(idx int) error {
+	return c.parent.SetAntenna(idx, c.parentIndex, c.IsRX)
 }
 
 // SetAntennaByName sets the current channel antenna port by name.
 // Example: LNAW
-func (c *LMSChannel) SetAntennaByName(name string) *LMSChannel {
-	c.parent.SetAntennaByName(name, c.parentIndex, c.IsRX)
-	return c
+func (c *LMSChannel) SetAntennaByName(name string) error {
+	return c.parent.SetAntennaByName(name, c.parentIndex, c.IsRX)
 }
 
 // SetCenterFrequency sets the current channel center frequency in hertz.
-func (c *LMSChannel) SetCenterFrequency(centerFrequency float64) *LMSChannel {
-	c.parent.SetCenterFrequency(c.parentIndex, c.IsRX, centerFrequency)
-	return c
+func (c *LMSChannel) SetCenterFrequency(centerFrequency float64) error {
+	return c.parent.SetCenterFrequency(c.parentIndex, c.IsRX, centerFrequency)
 }
 
 // GetCenterFrequency returns the current channel center frequency in hertz.
-func (c *LMSChannel) GetCenterFrequency() float64 {
+func (c *LMSChannel) GetCenterFrequency() (float64, error) {
 	return c.parent.GetCenterFrequency(c.parentIndex, c.IsRX)
 }
 
+// StreamStats returns the health of the channel stream, like overruns, underruns and link rate.
+// The channel must be enabled.
+func (c *LMSChannel) StreamStats() (StreamStats, error) {
+	return c.parent.GetStreamStats(c.parentIndex, c.IsRX)
+}
+
+// Calibrate runs the automatic calibration (DC offset and IQ imbalance) of the current channel for the bandwidth in hertz.
+func (c *LMSChannel) Calibrate(bandwidth float64) error {
+	return c.parent.Calibrate(c.parentIndex, c.IsRX, bandwidth)
+}
+
 // String returns a representation of the channel
 func (c *LMSChannel) String() string {
 	var str = fmt.Sprintf("\nIs RX: %t\nAntennas: %d", c.IsRX, len(c.Antennas))
@@ -129,14 +154,131 @@ func (c *LMSChannel) String() string {
 	return str
 }
 
-func (c *LMSChannel) start() {
-	if c.stream != nil {
-		limewrap.LMS_StartStream(c.stream)
+// WriteSamples queues samples to be transmitted by this TX channel as soon as possible.
+// The samples are copied, so the slice can be reused after the call.
+// It blocks while the TX queue is full, so the device should be started before writing more than txQueueDepth blocks.
+func (c *LMSChannel) WriteSamples(samples []complex64) error {
+	return c.queue("write samples", samples, false, 0)
+}
+
+// SendBurstAt queues a burst to be transmitted by this TX channel starting exactly at the hardware sample counter timestamp.
+// The end of the burst is flushed, so it does not wait for more samples to fill a packet.
+// Timestamps are in the same time base as the ones delivered to the RX callback.
+// It blocks while the TX queue is full, the same way as WriteSamples.
+func (c *LMSChannel) SendBurstAt(samples []complex64, timestamp uint64) error {
+	return c.queue("send burst", samples, true, timestamp)
+}
+
+func (c *LMSChannel) queue(op string, samples []complex64, burst bool, timestamp uint64) error {
+	if c.IsRX {
+		return c.parent.channelError(op, c.parentIndex, c.IsRX, nil, ErrWrongDirection)
+	}
+
+	if len(samples) == 0 {
+		return nil
+	}
+
+	var block = txBlock{
+		samples:   make([]complex64, len(samples)),
+		burst:     burst,
+		timestamp: timestamp,
+	}
+	copy(block.samples, samples)
+	c.txQueue <- block
+	return nil
+}
+
+// Samples returns a Go channel that receives the samples of this RX channel while the device is running.
+// It is an alternative to LMSDevice.SetCallback that does not block the other channels when the consumer is slow:
+// if the consumer falls behind more than the buffer depth (see SetSamplesDepth) the block is dropped and counted in StreamStats.
+// The Go channel is closed when the device stops or is closed, so ranging over it finishes with the stream.
+// The same Go channel is returned until then, and the next call returns a new one. It returns nil for TX channels.
+func (c *LMSChannel) Samples() <-chan SampleBlock {
+	if !c.IsRX {
+		return nil
+	}
+
+	c.samplesMtx.Lock()
+	defer c.samplesMtx.Unlock()
+	if c.samples == nil {
+		if c.samplesDepth <= 0 {
+			c.samplesDepth = defaultSamplesDepth
+		}
+		c.samples = make(chan SampleBlock, c.samplesDepth)
+	}
+	return c.samples
+}
+
+// SetSamplesDepth sets how many sample blocks are buffered in the Go channel returned by Samples.
+// It cannot be called while the device is running. A Go channel returned previously by Samples is closed,
+// and the next call to Samples returns a new one with the new depth.
+func (c *LMSChannel) SetSamplesDepth(depth int) error {
+	if !c.IsRX {
+		return c.parent.channelError("set samples depth", c.parentIndex, c.IsRX, nil, ErrWrongDirection)
+	}
+
+	if c.parent.running {
+		return c.parent.channelError("set samples depth", c.parentIndex, c.IsRX, nil, ErrAlreadyRunning)
 	}
+
+	if depth <= 0 {
+		return c.parent.channelError(fmt.Sprintf("set samples depth to %d", depth), c.parentIndex, c.IsRX, nil, ErrOutOfRange)
+	}
+
+	c.samplesMtx.Lock()
+	c.samplesDepth = depth
+	c.samplesMtx.Unlock()
+	c.closeSamples()
+	return nil
 }
 
-//func (c *LMSChannel) stop() {
-//	if c.stream != nil {
-//		limewrap.LMS_StopStream(c.stream)
-//	}
-//}
+// closeSamples closes the Go channel returned by Samples, if any. The next call to Samples creates a new one.
+func (c *LMSChannel) closeSamples() {
+	c.samplesMtx.Lock()
+	defer c.samplesMtx.Unlock()
+	if c.samples != nil {
+		close(c.samples)
+		c.samples = nil
+	}
+}
+
+// deliverSamples sends the block to the Go channel returned by Samples, if any, without blocking.
+// Returns true if the block was delivered.
+func (c *LMSChannel) deliverSamples(block SampleBlock) bool {
+	c.samplesMtx.Lock()
+	defer c.samplesMtx.Unlock()
+	if c.samples == nil {
+		return false
+	}
+
+	select {
+	case c.samples <- block:
+		return true
+	default:
+		atomic.AddUint64(&c.droppedBlocks, 1)
+		return false
+	}
+}
+
+// TXUnderruns returns how many times this TX channel ran out of samples to transmit while the device was running.
+func (c *LMSChannel) TXUnderruns() uint64 {
+	return atomic.LoadUint64(&c.txUnderruns)
+}
+
+func (c *LMSChannel) start() error {
+	if c.stream != 0 {
+		if err := c.parent.backend.StartStream(c.stream); err != nil {
+			return c.parent.channelError("start stream", c.parentIndex, c.IsRX, err, ErrStreamFailure)
+		}
+	}
+	return nil
+}
+
+func (c *LMSChannel) stop() error {
+	if c.stream != 0 {
+		if err := c.parent.backend.StopStream(c.stream); err != nil {
+			return c.parent.channelError("stop stream", c.parentIndex, c.IsRX, err, ErrStreamFailure)
+		}
+	}
+	return nil
+}
@@ -2,11 +2,10 @@ package limedrv
 
 import (
 	"fmt"
-	"github.com/racerxdl/limedrv/limewrap"
-	"os"
 	"runtime"
 	"strings"
-	"unsafe"
+	"sync"
+	"time"
 )
 
 // LMSDevice is a class representing a Open LimeSDR Device.
@@ -26,6 +25,9 @@ type LMSDevice struct {
 	// Notice that the callback from LMSDevice always returns complex64 which is converted internally by limedrv.
 	// This IQFormat only specifies what the driver receives from the device itself, reducing bus bandwidth.
 	IQFormat int
+	// UnscaledSamples disables the normalization of received integer samples.
+	// When set, FormatInt12 and FormatInt16 samples are delivered with their raw integer values instead of being divided by IQFormatFullScale.
+	UnscaledSamples bool
 
 	// RXLPFMaxFrequency is the maximum Analog Low Pass Filter Frequency Suported by the Receive Channels in Hertz
 	RXLPFMaxFrequency float64
@@ -39,193 +41,271 @@ type LMSDevice struct {
 	// Advanced is the object for advanced manipulation of the LMS Device itself. Use with care.
 	Advanced LMSDeviceAdvanced
 
+	// GPIO is the object to access the GPIO header of the LMS Device.
+	GPIO LMSGPIO
+
+	backend     Backend
 	dev         uintptr
 	controlChan chan bool
 	running     bool
 	callback    func([]complex64, int, uint64)
+	txCallback  func([]complex64, int) int
+	// callbackNoCopy tells the callback does not keep the samples, so their buffer can be reused
+	callbackNoCopy bool
+
+	statsCallback func(StreamStats)
+	statsInterval time.Duration
+
+	calibrationCallback       func(CalibrationResult)
+	autoCalFrequencyThreshold float64
+	autoCalLPFThreshold       float64
+
+	ppmCorrection     float64
+	externalReference float64
+
+	removedCallback func()
+	removed         int32
+	// devMtx guards dev between Close and the IsConnected checks of WatchDevices, and removedCallback
+	devMtx sync.Mutex
 }
 
 // region Private Methods
 
-func (d *LMSDevice) init() {
+func (d *LMSDevice) init() error {
 	runtime.LockOSThread()
 	defer runtime.UnlockOSThread()
-	if limewrap.LMS_Reset(d.dev) != 0 {
-		panic(fmt.Sprintf("Failed to reset %s at %s: %s", d.DeviceInfo.DeviceName, d.DeviceInfo.Media, limewrap.LMS_GetLastErrorMessage()))
+	if err := d.backend.Reset(d.dev); err != nil {
+		return d.deviceError("reset", err, nil)
+	}
+
+	if err := d.backend.Init(d.dev); err != nil {
+		return d.deviceError("init", err, nil)
+	}
+
+	if err := d.loadChannels(); err != nil {
+		return err
+	}
+
+	return d.initSampleRateRange()
+}
+
+// deviceError returns a LMSError for a device wide operation.
+// err is the error reported by the backend, if any. If sentinel is not nil, the underlying error matches it with errors.Is
+// and still unwraps to err.
+func (d *LMSDevice) deviceError(op string, err, sentinel error) error {
+	return d.channelError(op, -1, false, err, sentinel)
+}
+
+// channelError returns a LMSError for a channel operation.
+// err is the error reported by the backend, if any. If sentinel is not nil, the underlying error matches it with errors.Is
+// and still unwraps to err.
+func (d *LMSDevice) channelError(op string, channelNumber int, isRX bool, err, sentinel error) error {
+	var e = &LMSError{
+		Op:         op,
+		DeviceName: d.DeviceInfo.DeviceName,
+		Media:      d.DeviceInfo.Media,
+		Channel:    channelNumber,
+		IsRX:       isRX,
+		Err:        err,
 	}
 
-	if limewrap.LMS_Init(d.dev) != 0 {
-		panic(fmt.Sprintf("Failed to init %s at %s: %s", d.DeviceInfo.DeviceName, d.DeviceInfo.Media, limewrap.LMS_GetLastErrorMessage()))
+	if err != nil {
+		e.Message = err.Error()
 	}
 
-	d.loadChannels()
-	d.initSampleRateRange()
+	if sentinel != nil && err != nil {
+		e.Err = &sentinelError{sentinel: sentinel, err: err}
+	} else if sentinel != nil {
+		e.Err = sentinel
+	}
+
+	return e
 }
 
-func (d *LMSDevice) loadChannels() {
+// channels returns the RX or TX channels of the device
+func (d *LMSDevice) channels(isRX bool) []*LMSChannel {
+	if isRX {
+		return d.RXChannels
+	}
+	return d.TXChannels
+}
+
+// getChannel returns the LMSChannel for the specified channel number and direction
+func (d *LMSDevice) getChannel(channelNumber int, isRX bool) (*LMSChannel, error) {
+	var channels = d.channels(isRX)
+	if channelNumber < 0 || channelNumber >= len(channels) {
+		return nil, d.channelError("get channel", channelNumber, isRX, nil, ErrInvalidChannel)
+	}
+
+	return channels[channelNumber], nil
+}
+
+func (d *LMSDevice) loadChannels() error {
 	runtime.LockOSThread()
 	defer runtime.UnlockOSThread()
 	// region Load RX Channels
-	var bw = createLms_range_t()
-	limewrap.LMS_GetLPFBWRange(d.dev, limewrap.LmsChRx, bw)
+	bw, err := d.backend.GetLPFBWRange(d.dev, true)
+	if err != nil {
+		return d.deviceError("get RX LPF range", err, nil)
+	}
 
-	d.RXLPFMaxFrequency = bw.GetMax()
-	d.RXLPFMinFrequency = bw.GetMin()
+	d.RXLPFMaxFrequency = bw.Max
+	d.RXLPFMinFrequency = bw.Min
 
-	rxChannels := limewrap.LMS_GetNumChannels(d.dev, limewrap.LmsChRx)
-	d.RXChannels = make([]*LMSChannel, rxChannels)
-	for i := 0; i < rxChannels; i++ {
-		ch := LMSChannel{
-			IsRX:              true,
-			parent:            d,
-			parentIndex:       i,
-			advancedFiltering: false,
-		}
-		antennas := limewrap.LMS_GetAntennaList(d.dev, limewrap.LmsChRx, int64(i), nil)
-		ch.Antennas = make([]LMSAntenna, antennas)
-
-		if antennas > 0 {
-			var nameArr [16 * 20]byte // 16 bytes per lms_name_t
-			var namePtr = (*string)(unsafe.Pointer(&nameArr[0]))
-			limewrap.LMS_GetAntennaList(d.dev, limewrap.LmsChRx, int64(i), namePtr)
-			for a := 0; a < antennas; a++ {
-				var name = cleanString(string(nameArr[a*16 : (a+1)*16]))
-				limewrap.LMS_GetAntennaBW(d.dev, limewrap.LmsChRx, int64(i), int64(a), bw)
-				ch.Antennas[a] = LMSAntenna{
-					Name:             name,
-					Channel:          i,
-					MaximumFrequency: bw.GetMax(),
-					MinimumFrequency: bw.GetMin(),
-					Step:             bw.GetStep(),
-					parent:           &ch,
-					index:            a,
-				}
-			}
-		}
-
-		d.RXChannels[i] = &ch
+	d.RXChannels, err = d.loadChannelList(true)
+	if err != nil {
+		return err
 	}
 	// endregion
 	// region Load TX Channels
-	limewrap.LMS_GetLPFBWRange(d.dev, limewrap.LmsChTx, bw)
+	bw, err = d.backend.GetLPFBWRange(d.dev, false)
+	if err != nil {
+		return d.deviceError("get TX LPF range", err, nil)
+	}
+
+	d.TXLPFMaxFrequency = bw.Max
+	d.TXLPFMinFrequency = bw.Min
+
+	d.TXChannels, err = d.loadChannelList(false)
+	return err
+	// endregion
+}
 
-	d.TXLPFMaxFrequency = bw.GetMax()
-	d.TXLPFMinFrequency = bw.GetMin()
+func (d *LMSDevice) loadChannelList(isRX bool) ([]*LMSChannel, error) {
+	numChannels, err := d.backend.GetNumChannels(d.dev, isRX)
+	if err != nil {
+		return nil, d.deviceError("get number of channels", err, nil)
+	}
 
-	txChannels := limewrap.LMS_GetNumChannels(d.dev, limewrap.LmsChTx)
-	d.TXChannels = make([]*LMSChannel, txChannels)
-	for i := 0; i < txChannels; i++ {
+	channels := make([]*LMSChannel, numChannels)
+	for i := 0; i < numChannels; i++ {
 		ch := LMSChannel{
-			IsRX:              false,
+			IsRX:              isRX,
 			parent:            d,
 			parentIndex:       i,
+			ncoIndex:          -1,
 			advancedFiltering: false,
 		}
-		antennas := limewrap.LMS_GetAntennaList(d.dev, limewrap.LmsChTx, int64(i), nil)
-		ch.Antennas = make([]LMSAntenna, antennas)
-
-		if antennas > 0 {
-			var nameArr [16 * 64]byte // 16 bytes per lms_name_t
-			var namePtr = (*string)(unsafe.Pointer(&nameArr[0]))
-			limewrap.LMS_GetAntennaList(d.dev, limewrap.LmsChTx, int64(i), namePtr)
-			for a := 0; a < antennas; a++ {
-				var name = cleanString(string(nameArr[a*16 : (a+1)*16]))
-				limewrap.LMS_GetAntennaBW(d.dev, limewrap.LmsChTx, int64(i), int64(a), bw)
-				ch.Antennas[a] = LMSAntenna{
-					Name:             name,
-					Channel:          i,
-					MaximumFrequency: bw.GetMax(),
-					MinimumFrequency: bw.GetMin(),
-					Step:             bw.GetStep(),
-					parent:           &ch,
-					index:            a,
-				}
+
+		if !isRX {
+			ch.txQueue = make(chan txBlock, txQueueDepth)
+		}
+
+		names, err := d.backend.GetAntennaList(d.dev, isRX, i)
+		if err != nil {
+			return nil, d.channelError("get antenna list", i, isRX, err, nil)
+		}
+
+		ch.Antennas = make([]LMSAntenna, len(names))
+		for a, name := range names {
+			bw, err := d.backend.GetAntennaBW(d.dev, isRX, i, a)
+			if err != nil {
+				return nil, d.channelError("get antenna bandwidth", i, isRX, err, nil)
+			}
+			ch.Antennas[a] = LMSAntenna{
+				Name:             name,
+				Channel:          i,
+				MaximumFrequency: bw.Max,
+				MinimumFrequency: bw.Min,
+				Step:             bw.Step,
+				parent:           &ch,
+				index:            a,
 			}
 		}
 
-		d.TXChannels[i] = &ch
+		channels[i] = &ch
 	}
-	// endregion
+
+	return channels, nil
 }
 
-func (d *LMSDevice) initSampleRateRange() {
+func (d *LMSDevice) initSampleRateRange() error {
 	runtime.LockOSThread()
 	defer runtime.UnlockOSThread()
-	var bw = createLms_range_t()
-	if limewrap.LMS_GetSampleRateRange(d.dev, limewrap.LmsChRx, bw) != 0 {
-		panic(fmt.Sprintf("Failed to get sample rate range %s at %s: %s", d.DeviceInfo.DeviceName, d.DeviceInfo.Media, limewrap.LMS_GetLastErrorMessage()))
+	bw, err := d.backend.GetSampleRateRange(d.dev, true)
+	if err != nil {
+		return d.deviceError("get sample rate range", err, nil)
 	}
 
-	d.MinimumSampleRate = bw.GetMin()
-	d.MaximumSampleRate = bw.GetMax()
-	d.SetSampleRate(1e6, 4)
+	d.MinimumSampleRate = bw.Min
+	d.MaximumSampleRate = bw.Max
+	return d.SetSampleRate(1e6, 4)
 }
 
-func (d *LMSDevice) setupStream(channelNumber int, isRX bool) {
+func (d *LMSDevice) setupStream(channelNumber int, isRX bool) error {
 	runtime.LockOSThread()
 	defer runtime.UnlockOSThread()
-	var ch *LMSChannel
-
-	if isRX {
-		ch = d.RXChannels[channelNumber]
-	} else {
-		ch = d.TXChannels[channelNumber]
+	ch, err := d.getChannel(channelNumber, isRX)
+	if err != nil {
+		return err
 	}
 
-	if ch.stream != nil {
-		limewrap.LMS_DestroyStream(d.dev, ch.stream)
-		ch.stream = nil
+	if ch.stream != 0 {
+		d.backend.DestroyStream(d.dev, ch.stream)
+		ch.stream = 0
 	}
 
-	var s = createLms_stream_t()
-	s.SetChannel(uint(channelNumber))
-	s.SetDataFmt(d.IQFormat)
-	s.SetFifoSize(32 * fifoSize)
-	s.SetIsTx(!isRX)
-	s.SetThroughputVsLatency(0.5)
+	stream, err := d.backend.SetupStream(d.dev, StreamConfig{
+		Channel:             channelNumber,
+		IsRX:                isRX,
+		Format:              d.IQFormat,
+		FifoSize:            32 * fifoSize,
+		ThroughputVsLatency: 0.5,
+	})
 
-	if limewrap.LMS_SetupStream(d.dev, s) != 0 {
-		panic(fmt.Sprintf("Failed to set stream in %s at %s: %s", d.DeviceInfo.DeviceName, d.DeviceInfo.Media, limewrap.LMS_GetLastErrorMessage()))
+	if err != nil {
+		return d.channelError("setup stream", channelNumber, isRX, err, ErrStreamFailure)
 	}
 
-	ch.stream = s
+	ch.stream = stream
+	ch.resetStreamStats()
+	return nil
 }
 
 func (d *LMSDevice) deviceLoop() {
 
-	var cachedActiveChannels = make([]LMSChannel, 0)
+	var cachedActiveChannels = make([]*LMSChannel, 0)
 
 	lmsDataChannel := make(chan channelMessage)
 
 	// Check active
 	for i := 0; i < len(d.RXChannels); i++ {
 		var ch = d.RXChannels[i]
-		if ch.stream != nil {
-			cachedActiveChannels = append(cachedActiveChannels, *ch)
+		if ch.stream != 0 {
+			cachedActiveChannels = append(cachedActiveChannels, ch)
 		}
 	}
-	// TODO: TX
-	//for i := 0; i < len(d.TXChannels); i++ {
-	//	var ch = d.TXChannels[i]
-	//	if ch.stream != nil {
-	//		cachedActiveChannels = append(cachedActiveChannels, ch)
-	//		ch.start()
-	//	}
-	//}
 
 	streamControl := make([]chan bool, len(cachedActiveChannels))
 
 	for i := 0; i < len(cachedActiveChannels); i++ {
 		streamControl[i] = make(chan bool)
 		ch := cachedActiveChannels[i]
-		ch.start()
 		go streamLoop(lmsDataChannel, streamControl[i], ch)
 	}
 
+	var activeTXChannels = make([]*LMSChannel, 0)
+	var txControl = make([]chan bool, 0)
+	for i := 0; i < len(d.TXChannels); i++ {
+		var ch = d.TXChannels[i]
+		if ch.stream != 0 {
+			con := make(chan bool)
+			activeTXChannels = append(activeTXChannels, ch)
+			txControl = append(txControl, con)
+			go txStreamLoop(con, ch)
+		}
+	}
+
+	var statsTicker <-chan time.Time
+	if d.statsCallback != nil && d.statsInterval > 0 {
+		ticker := time.NewTicker(d.statsInterval)
+		defer ticker.Stop()
+		statsTicker = ticker.C
+	}
+
 	// Notify Main thread that we're done caching
-	//log.Println("Device Loop ready.")
+	d.log(LogDebug, -1, false, nil, "device loop running with %d RX and %d TX channels", len(cachedActiveChannels), len(activeTXChannels))
 	d.controlChan <- true
-	//log.Println("Device Loop running with", len(cachedActiveChannels), "channels")
 	running := true
 	for running {
 		select {
@@ -234,269 +314,407 @@ func (d *LMSDevice) deviceLoop() {
 		case msg := <-lmsDataChannel:
 			if d.callback != nil {
 				d.callback(msg.data, msg.channel, msg.timestamp)
+				if !d.callbackNoCopy {
+					// The callback owns the data now
+					msg.buffer = nil
+				}
 			}
+			msg.releaseSamples()
+		case <-statsTicker:
+			d.reportStreamStats(cachedActiveChannels)
+			d.reportStreamStats(activeTXChannels)
 		}
 	}
 
 	// Wait for stopping streams
-	//log.Println("Stopping streams")
+	d.log(LogDebug, -1, false, nil, "stopping streams")
 	for i := 0; i < len(streamControl); i++ {
-		select {
-		case streamControl[i] <- true: // Send close signal
-		case <-lmsDataChannel: // Discard any data received in channel
+		for stopped := false; !stopped; {
+			select {
+			case streamControl[i] <- true: // Send close signal
+				stopped = true
+			case msg := <-lmsDataChannel: // Discard any data received in channel
+				msg.releaseSamples()
+			}
 		}
 	}
+	for i := 0; i < len(txControl); i++ {
+		txControl[i] <- true
+	}
 	d.controlChan <- true
 }
 
+// reportStreamStats sends the stream stats of the channels to the stats callback
+func (d *LMSDevice) reportStreamStats(channels []*LMSChannel) {
+	for _, ch := range channels {
+		if stats, err := ch.readStreamStats(); err == nil {
+			d.statsCallback(stats)
+		}
+	}
+}
+
 // endregion
 // region Public Methods
 // SetCallback sets the callback for samples.
+// The callback owns the samples slice it receives, so it can keep it after returning.
 func (d *LMSDevice) SetCallback(cb func([]complex64, int, uint64)) {
 	d.callback = cb
+	d.callbackNoCopy = false
+}
+
+// SetCallbackNoCopy sets the callback for samples like SetCallback, but the samples slice is reused after the callback returns
+// to avoid allocating a buffer for every block. The callback should copy the samples it needs to keep.
+func (d *LMSDevice) SetCallbackNoCopy(cb func([]complex64, int, uint64)) {
+	d.callback = cb
+	d.callbackNoCopy = true
+}
+
+// SetTXCallback sets the producer of samples for the enabled TX channels.
+// It is called from the TX stream loop with a buffer to be filled and the channel number,
+// and should return how many samples were written to the buffer. Returning 0 counts as an underrun.
+// Counts outside the buffer length are logged and limited to it.
+// Samples queued with LMSChannel.WriteSamples or LMSChannel.SendBurstAt take precedence over the TX callback.
+func (d *LMSDevice) SetTXCallback(cb func([]complex64, int) int) {
+	d.txCallback = cb
+}
+
+// SetStatsCallback sets a callback that receives the StreamStats of every enabled channel at each interval while the device is running.
+// It is called from the device loop, the same goroutine that calls the sample callback.
+func (d *LMSDevice) SetStatsCallback(interval time.Duration, cb func(StreamStats)) {
+	d.statsInterval = interval
+	d.statsCallback = cb
+}
+
+// GetStreamStats returns the health of the stream in the specified channel. The channel must be enabled.
+func (d *LMSDevice) GetStreamStats(channelNumber int, isRX bool) (StreamStats, error) {
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+	ch, err := d.getChannel(channelNumber, isRX)
+	if err != nil {
+		return StreamStats{}, err
+	}
+	return ch.readStreamStats()
 }
 
 // SetGainDB Sets the gain of the channel to specified value in dB
-func (d *LMSDevice) SetGainDB(channelNumber int, isRX bool, gain uint) {
+func (d *LMSDevice) SetGainDB(channelNumber int, isRX bool, gain uint) error {
 	runtime.LockOSThread()
 	defer runtime.UnlockOSThread()
-	if limewrap.LMS_SetGaindB(d.dev, !isRX, int64(channelNumber), gain) != 0 {
-		panic(fmt.Sprintf("Failed to set channel gain in %s at %s: %s", d.DeviceInfo.DeviceName, d.DeviceInfo.Media, limewrap.LMS_GetLastErrorMessage()))
+	if err := d.backend.SetGaindB(d.dev, isRX, channelNumber, gain); err != nil {
+		return d.channelError("set channel gain", channelNumber, isRX, err, nil)
 	}
+	return nil
 }
 
 // SetGainNormalized sets the gain of the channel to specified normalized value [0-1] with 0 being no gain, 1 being maximum gain.
-func (d *LMSDevice) SetGainNormalized(channelNumber int, isRX bool, gain float64) {
+func (d *LMSDevice) SetGainNormalized(channelNumber int, isRX bool, gain float64) error {
 	runtime.LockOSThread()
 	defer runtime.UnlockOSThread()
-	if limewrap.LMS_SetNormalizedGain(d.dev, !isRX, int64(channelNumber), gain) != 0 {
-		panic(fmt.Sprintf("Failed to set channel gain in %s at %s: %s", d.DeviceInfo.DeviceName, d.DeviceInfo.Media, limewrap.LMS_GetLastErrorMessage()))
+	if err := d.backend.SetNormalizedGain(d.dev, isRX, channelNumber, gain); err != nil {
+		return d.channelError("set channel gain", channelNumber, isRX, err, nil)
 	}
+	return nil
 }
 
 // GetGainDB returns the currently set gain in specified channel
-func (d *LMSDevice) GetGainDB(channelNumber int, isRX bool) (gain uint) {
+func (d *LMSDevice) GetGainDB(channelNumber int, isRX bool) (gain uint, err error) {
 	runtime.LockOSThread()
 	defer runtime.UnlockOSThread()
-	if limewrap.LMS_GetGaindB(d.dev, !isRX, int64(channelNumber), &gain) != 0 {
-		panic(fmt.Sprintf("Failed to get channel gain in %s at %s: %s", d.DeviceInfo.DeviceName, d.DeviceInfo.Media, limewrap.LMS_GetLastErrorMessage()))
+	if gain, err = d.backend.GetGaindB(d.dev, isRX, channelNumber); err != nil {
+		return 0, d.channelError("get channel gain", channelNumber, isRX, err, nil)
 	}
-	return gain
+	return gain, nil
 }
 
 // GetGainNormalized returns the currently set gain in specified channel
-func (d *LMSDevice) GetGainNormalized(channelNumber int, isRX bool) (gain float64) {
+func (d *LMSDevice) GetGainNormalized(channelNumber int, isRX bool) (gain float64, err error) {
 	runtime.LockOSThread()
 	defer runtime.UnlockOSThread()
-	if limewrap.LMS_GetNormalizedGain(d.dev, !isRX, int64(channelNumber), &gain) != 0 {
-		panic(fmt.Sprintf("Failed to get channel gain in %s at %s: %s", d.DeviceInfo.DeviceName, d.DeviceInfo.Media, limewrap.LMS_GetLastErrorMessage()))
+	if gain, err = d.backend.GetNormalizedGain(d.dev, isRX, channelNumber); err != nil {
+		return 0, d.channelError("get channel gain", channelNumber, isRX, err, nil)
 	}
-	return gain
+	return gain, nil
 }
 
 // GetTemperature returns the temperature in degrees celsius of the LMS Device
-func (d *LMSDevice) GetTemperature() (temp float64) {
+func (d *LMSDevice) GetTemperature() (temp float64, err error) {
 	runtime.LockOSThread()
 	defer runtime.UnlockOSThread()
-	if limewrap.LMS_GetChipTemperature(d.dev, 0, &temp) != 0 {
-		panic(fmt.Sprintf("Failed to get chip temperature in %s at %s: %s", d.DeviceInfo.DeviceName, d.DeviceInfo.Media, limewrap.LMS_GetLastErrorMessage()))
+	if temp, err = d.backend.GetChipTemperature(d.dev, 0); err != nil {
+		return 0, d.deviceError("get chip temperature", err, nil)
 	}
-	return temp
+	return temp, nil
 }
 
 // SetLPF sets the analog Low Pass Filter bandwidth for the specified channel.
 // bandwidth is passed in Hertz
-func (d *LMSDevice) SetLPF(channelNumber int, isRX bool, bandwidth float64) {
+func (d *LMSDevice) SetLPF(channelNumber int, isRX bool, bandwidth float64) error {
 	runtime.LockOSThread()
 	defer runtime.UnlockOSThread()
-	if limewrap.LMS_SetLPFBW(d.dev, !isRX, int64(channelNumber), bandwidth) != 0 {
-		panic(fmt.Sprintf("Failed to set LPF Bandwidth in %s at %s: %s", d.DeviceInfo.DeviceName, d.DeviceInfo.Media, limewrap.LMS_GetLastErrorMessage()))
+	if err := d.backend.SetLPFBW(d.dev, isRX, channelNumber, bandwidth); err != nil {
+		return d.channelError("set LPF bandwidth", channelNumber, isRX, err, nil)
+	}
+
+	ch, err := d.getChannel(channelNumber, isRX)
+	if err != nil {
+		return err
 	}
+	ch.lpfBandwidth = bandwidth
+	return d.recalibrate(ch)
 }
 
 // GetLPF gets the analog Low Pass Filter bandwidth in Hertz
-func (d *LMSDevice) GetLPF(channelNumber int, isRX bool) (bandwidth float64) {
+func (d *LMSDevice) GetLPF(channelNumber int, isRX bool) (bandwidth float64, err error) {
 	runtime.LockOSThread()
 	defer runtime.UnlockOSThread()
-	if limewrap.LMS_GetLPFBW(d.dev, !isRX, int64(channelNumber), &bandwidth) != 0 {
-		panic(fmt.Sprintf("Failed to get LPF Bandwidth in %s at %s: %s", d.DeviceInfo.DeviceName, d.DeviceInfo.Media, limewrap.LMS_GetLastErrorMessage()))
+	if bandwidth, err = d.backend.GetLPFBW(d.dev, isRX, channelNumber); err != nil {
+		return 0, d.channelError("get LPF bandwidth", channelNumber, isRX, err, nil)
 	}
 
-	return bandwidth
+	return bandwidth, nil
 }
 
 // EnableLPF enables the Analog Low Pass filter in specified channel
-func (d *LMSDevice) EnableLPF(channelNumber int, isRX bool) {
+func (d *LMSDevice) EnableLPF(channelNumber int, isRX bool) error {
 	runtime.LockOSThread()
 	defer runtime.UnlockOSThread()
-	if limewrap.LMS_SetLPF(d.dev, !isRX, int64(channelNumber), true) != 0 {
-		panic(fmt.Sprintf("Failed to enable LPF in %s at %s: %s", d.DeviceInfo.DeviceName, d.DeviceInfo.Media, limewrap.LMS_GetLastErrorMessage()))
+	if err := d.backend.SetLPF(d.dev, isRX, channelNumber, true); err != nil {
+		return d.channelError("enable LPF", channelNumber, isRX, err, nil)
 	}
+	return nil
 }
 
 // DisableLPF disables the Analog Low Pass filter in the specified channel
-func (d *LMSDevice) DisableLPF(channelNumber int, isRX bool) {
+func (d *LMSDevice) DisableLPF(channelNumber int, isRX bool) error {
 	runtime.LockOSThread()
 	defer runtime.UnlockOSThread()
-	if limewrap.LMS_SetLPF(d.dev, !isRX, int64(channelNumber), false) != 0 {
-		panic(fmt.Sprintf("Failed to disable LPF in %s at %s: %s", d.DeviceInfo.DeviceName, d.DeviceInfo.Media, limewrap.LMS_GetLastErrorMessage()))
+	if err := d.backend.SetLPF(d.dev, isRX, channelNumber, false); err != nil {
+		return d.channelError("disable LPF", channelNumber, isRX, err, nil)
 	}
+	return nil
 }
 
 // SetDigitalFilter sets the Digital (GFIR) Low Pass filter frequency for the specified channel.
 // bandwidth in hertz
 // Requires Sample Rate to be set before calling this.
-func (d *LMSDevice) SetDigitalFilter(channelNumber int, isRX bool, bandwidth float64) {
+func (d *LMSDevice) SetDigitalFilter(channelNumber int, isRX bool, bandwidth float64) error {
 	runtime.LockOSThread()
 	defer runtime.UnlockOSThread()
-	var ch *LMSChannel
-	if isRX {
-		ch = d.RXChannels[channelNumber]
-	} else {
-		ch = d.TXChannels[channelNumber]
+	ch, err := d.getChannel(channelNumber, isRX)
+	if err != nil {
+		return err
 	}
 
-	ch.advancedFiltering = false
+	if bandwidth == 0 {
+		return d.channelError("set digital LPF", channelNumber, isRX, nil, ErrNoBandwidth)
+	}
 
+	ch.advancedFiltering = false
 	ch.currentDigitalBandwidth = bandwidth
 
-	if ch.currentDigitalBandwidth == 0 {
-		panic(fmt.Sprintf("Cannot enable digital filter at channel %d because no bandwidth is set! Call SetDigitalFilter first.", channelNumber))
+	if err := d.backend.SetGFIRLPF(d.dev, isRX, channelNumber, ch.digitalFilterEnabled, ch.currentDigitalBandwidth); err != nil {
+		return d.channelError("set digital LPF", channelNumber, isRX, err, nil)
 	}
-
-	limewrap.LMS_SetGFIRLPF(d.dev, isRX, int64(channelNumber), ch.digitalFilterEnabled, ch.currentDigitalBandwidth)
+	return nil
 }
 
 // EnableDigitalFilter enables the digital (GFIR) Low pass filter for specified channel.
-func (d *LMSDevice) EnableDigitalFilter(channelNumber int, isRX bool) {
+func (d *LMSDevice) EnableDigitalFilter(channelNumber int, isRX bool) error {
 	runtime.LockOSThread()
 	defer runtime.UnlockOSThread()
-	var ch *LMSChannel
-	if isRX {
-		ch = d.RXChannels[channelNumber]
-	} else {
-		ch = d.TXChannels[channelNumber]
+	ch, err := d.getChannel(channelNumber, isRX)
+	if err != nil {
+		return err
 	}
 
-	if !ch.advancedFiltering {
-		if ch.currentDigitalBandwidth == 0 {
-			panic(fmt.Sprintf("Cannot enable digital filter at channel %d because no bandwidth is set! Call SetDigitalFilter first.", channelNumber))
-		}
+	if ch.advancedFiltering {
+		// Manual taps are set. EnableGFir from Advanced should be used instead
+		return d.channelError("enable digital LPF", channelNumber, isRX, nil, ErrAdvancedFiltering)
+	}
 
-		if limewrap.LMS_SetGFIRLPF(d.dev, isRX, int64(channelNumber), true, ch.currentDigitalBandwidth) != 0 {
-			panic(fmt.Sprintf("Failed to enable Digital LPF in %s at %s: %s", d.DeviceInfo.DeviceName, d.DeviceInfo.Media, limewrap.LMS_GetLastErrorMessage()))
-		}
-	} else {
-		panic("Advanced Filtering is enabled. Please use EnableGFIR instead")
+	if ch.currentDigitalBandwidth == 0 {
+		// SetDigitalFilter should be called first
+		return d.channelError("enable digital LPF", channelNumber, isRX, nil, ErrNoBandwidth)
+	}
+
+	if err := d.backend.SetGFIRLPF(d.dev, isRX, channelNumber, true, ch.currentDigitalBandwidth); err != nil {
+		return d.channelError("enable digital LPF", channelNumber, isRX, err, nil)
 	}
 
 	ch.digitalFilterEnabled = true
+	return nil
 }
 
 // DisableDigitalFilter disables digital (GFIR) Low Pass filter for specified channel.
-func (d *LMSDevice) DisableDigitalFilter(channelNumber int, isRX bool) {
+func (d *LMSDevice) DisableDigitalFilter(channelNumber int, isRX bool) error {
 	runtime.LockOSThread()
 	defer runtime.UnlockOSThread()
-	var ch *LMSChannel
-	if isRX {
-		ch = d.RXChannels[channelNumber]
-	} else {
-		ch = d.TXChannels[channelNumber]
+	ch, err := d.getChannel(channelNumber, isRX)
+	if err != nil {
+		return err
 	}
 
-	if !ch.advancedFiltering {
-		if limewrap.LMS_SetGFIRLPF(d.dev, isRX, int64(channelNumber), false, ch.currentDigitalBandwidth) != 0 {
-			panic(fmt.Sprintf("Failed to disable Digital LPF in %s at %s: %s", d.DeviceInfo.DeviceName, d.DeviceInfo.Media, limewrap.LMS_GetLastErrorMessage()))
-		}
-	} else {
-		panic("Advanced Filtering is enabled. Please use DisableGFIR instead")
+	if ch.advancedFiltering {
+		// Manual taps are set. DisableGFir from Advanced should be used instead
+		return d.channelError("disable digital LPF", channelNumber, isRX, nil, ErrAdvancedFiltering)
+	}
+
+	if err := d.backend.SetGFIRLPF(d.dev, isRX, channelNumber, false, ch.currentDigitalBandwidth); err != nil {
+		return d.channelError("disable digital LPF", channelNumber, isRX, err, nil)
 	}
+
 	ch.digitalFilterEnabled = false
+	return nil
 }
 
 // EnableChannel enables a channel to be received in callback
-func (d *LMSDevice) EnableChannel(channelNumber int, isRX bool) {
+func (d *LMSDevice) EnableChannel(channelNumber int, isRX bool) error {
 	runtime.LockOSThread()
 	defer runtime.UnlockOSThread()
-	if limewrap.LMS_EnableChannel(d.dev, !isRX, int64(channelNumber), true) != 0 {
-		panic(fmt.Sprintf("Failed to enable channel in %s at %s: %s", d.DeviceInfo.DeviceName, d.DeviceInfo.Media, limewrap.LMS_GetLastErrorMessage()))
+	if err := d.backend.EnableChannel(d.dev, isRX, channelNumber, true); err != nil {
+		return d.channelError("enable channel", channelNumber, isRX, err, nil)
+	}
+
+	if err := d.setupStream(channelNumber, isRX); err != nil {
+		return err
+	}
+
+	ch, err := d.getChannel(channelNumber, isRX)
+	if err != nil {
+		return err
 	}
-	d.setupStream(channelNumber, isRX)
+	ch.enabled = true
+	return nil
 }
 
 // DisableChannel disables a channel to be received in callback
-func (d *LMSDevice) DisableChannel(channelNumber int, isRX bool) {
+func (d *LMSDevice) DisableChannel(channelNumber int, isRX bool) error {
 	runtime.LockOSThread()
 	defer runtime.UnlockOSThread()
-	if limewrap.LMS_EnableChannel(d.dev, !isRX, int64(channelNumber), false) != 0 {
-		panic(fmt.Sprintf("Failed to disable channel in %s at %s: %s", d.DeviceInfo.DeviceName, d.DeviceInfo.Media, limewrap.LMS_GetLastErrorMessage()))
+	if err := d.backend.EnableChannel(d.dev, isRX, channelNumber, false); err != nil {
+		return d.channelError("disable channel", channelNumber, isRX, err, nil)
 	}
+
+	ch, err := d.getChannel(channelNumber, isRX)
+	if err != nil {
+		return err
+	}
+	ch.enabled = false
+	return nil
 }
 
 // SetAntenna sets the input antenna for the specified channel.
-func (d *LMSDevice) SetAntenna(antennaNumber, channelNumber int, isRX bool) {
+func (d *LMSDevice) SetAntenna(antennaNumber, channelNumber int, isRX bool) error {
 	runtime.LockOSThread()
 	defer runtime.UnlockOSThread()
-	if limewrap.LMS_SetAntenna(d.dev, !isRX, int64(channelNumber), int64(antennaNumber)) != 0 {
-		panic(fmt.Sprintf("Failed to set antenna in %s at %s: %s", d.DeviceInfo.DeviceName, d.DeviceInfo.Media, limewrap.LMS_GetLastErrorMessage()))
+	if err := d.backend.SetAntenna(d.dev, isRX, channelNumber, antennaNumber); err != nil {
+		return d.channelError("set antenna", channelNumber, isRX, err, nil)
 	}
+	return nil
 }
 
 // SetAntennaByName sets the input antenna for the specified channel by using its representation name, for example LNAW
-func (d *LMSDevice) SetAntennaByName(name string, channelNumber int, isRX bool) {
+func (d *LMSDevice) SetAntennaByName(name string, channelNumber int, isRX bool) error {
 	runtime.LockOSThread()
 	defer runtime.UnlockOSThread()
-	var ant *LMSAntenna
-	if isRX {
-		var c = d.RXChannels[channelNumber]
-		for i := 0; i < len(c.Antennas); i++ {
-			var a = &c.Antennas[i]
-			if strings.ToLower(a.Name) == strings.ToLower(name) {
-				ant = a
-				break
-			}
+	c, err := d.getChannel(channelNumber, isRX)
+	if err != nil {
+		return err
+	}
+
+	for i := 0; i < len(c.Antennas); i++ {
+		var a = &c.Antennas[i]
+		if strings.EqualFold(a.Name, name) {
+			return a.Set()
 		}
-	} else {
-		var c = d.TXChannels[channelNumber]
-		for i := 0; i < len(c.Antennas); i++ {
-			var a = &c.Antennas[i]
-			if strings.ToLower(a.Name) == strings.ToLower(name) {
-				ant = a
-				break
-			}
+	}
+
+	return d.channelError(fmt.Sprintf("find antenna %s", name), channelNumber, isRX, nil, ErrAntennaNotFound)
+}
+
+// startStreams starts the streams of all enabled channels and keys the PTT if any TX channel is enabled
+func (d *LMSDevice) startStreams() error {
+	for i := 0; i < len(d.RXChannels); i++ {
+		if err := d.RXChannels[i].start(); err != nil {
+			return err
 		}
 	}
 
-	if ant == nil {
-		panic(fmt.Sprintf("Cannot find antenna with name %s.", name))
+	var transmitting = false
+	for i := 0; i < len(d.TXChannels); i++ {
+		if err := d.TXChannels[i].start(); err != nil {
+			return err
+		}
+		transmitting = transmitting || d.TXChannels[i].stream != 0
 	}
 
-	ant.Set()
+	if transmitting {
+		return d.GPIO.ptt(true)
+	}
+	return nil
 }
 
-// Start starts the device loop
-func (d *LMSDevice) Start() {
-	if !d.running {
-		d.running = true
-		go d.deviceLoop()
-		//log.Println("Waiting for device loop be ready")
-		<-d.controlChan
-		//log.Println("Device started")
-	} else {
-		fmt.Fprintf(os.Stderr, "Device already running")
+// stopStreams stops the streams of all channels and releases the PTT. The first error is returned, but all are stopped.
+func (d *LMSDevice) stopStreams() error {
+	var err error
+	for i := 0; i < len(d.RXChannels); i++ {
+		if e := d.RXChannels[i].stop(); e != nil && err == nil {
+			err = e
+		}
+	}
+	for i := 0; i < len(d.TXChannels); i++ {
+		if e := d.TXChannels[i].stop(); e != nil && err == nil {
+			err = e
+		}
+	}
+	if e := d.GPIO.ptt(false); e != nil && err == nil {
+		err = e
 	}
+	return err
 }
 
-// Stop stops the device loop
-func (d *LMSDevice) Stop() {
+// Start starts the device loop, streaming all enabled RX and TX channels.
+// If a stream fails to start, the ones already started are stopped again.
+func (d *LMSDevice) Start() error {
 	if d.running {
-		d.running = false
-		d.controlChan <- false
-		//log.Println("Waiting loop to stop")
-		<-d.controlChan
+		return d.deviceError("start", nil, ErrAlreadyRunning)
+	}
+
+	if err := d.startStreams(); err != nil {
+		// Stop cannot be called for a device that is not running, so nothing should be left streaming or keyed
+		d.stopStreams()
+		d.log(LogError, -1, false, err, "failed to start")
+		return err
+	}
+
+	d.running = true
+	go d.deviceLoop()
+	<-d.controlChan
+	d.log(LogInfo, -1, false, nil, "started")
+	return nil
+}
+
+// Stop stops the device loop
+func (d *LMSDevice) Stop() error {
+	if !d.running {
+		return d.deviceError("stop", nil, ErrNotRunning)
+	}
+
+	d.running = false
+	d.controlChan <- false
+	<-d.controlChan
+
+	var err = d.stopStreams()
+	for i := 0; i < len(d.RXChannels); i++ {
+		d.RXChannels[i].closeSamples()
+	}
+
+	if err != nil {
+		d.log(LogError, -1, false, err, "failed to stop")
 	} else {
-		fmt.Fprintf(os.Stderr, "Device not running")
+		d.log(LogInfo, -1, false, nil, "stopped")
 	}
+	return err
 }
 
 // SetSampleRate sets the sampleRate for specified value.
@@ -504,55 +722,76 @@ func (d *LMSDevice) Stop() {
 // for example if you set 1e6 for the sample rate and a oversample to 8,
 // the limesdr hardware will run at 8e6 sps and decimate by 8 before sending to the FPGA
 // this way you can increase the resolution without affecting the bandwidth to the computer
-func (d *LMSDevice) SetSampleRate(sampleRate float64, oversample int) {
+func (d *LMSDevice) SetSampleRate(sampleRate float64, oversample int) error {
 	runtime.LockOSThread()
 	defer runtime.UnlockOSThread()
-	if limewrap.LMS_SetSampleRate(d.dev, sampleRate, int64(oversample)) != 0 {
-		panic(fmt.Sprintf("Failed to set SampleRate to %f in %s at %s: %s", sampleRate, d.DeviceInfo.DeviceName, d.DeviceInfo.Media, limewrap.LMS_GetLastErrorMessage()))
+	if err := d.backend.SetSampleRate(d.dev, d.toHardwareFrequency(sampleRate), oversample); err != nil {
+		return d.deviceError(fmt.Sprintf("set sample rate to %f", sampleRate), err, nil)
 	}
+	return nil
 }
 
 // GetSampleRate returns both host sample rate and rf sample rate (defined by oversample)
 // If a SetSampleRate has been called with samplerate of 1e6 and overSample of 8,
 // This call will return 1e6 in host and 8e6 in rf.
-func (d *LMSDevice) GetSampleRate() (host float64, rf float64) {
+func (d *LMSDevice) GetSampleRate() (host float64, rf float64, err error) {
 	runtime.LockOSThread()
 	defer runtime.UnlockOSThread()
-	host = float64(0)
-	rf = float64(0)
-	//LMS_GetSampleRate (lms_device_t *device, bool dir_tx, size_t chan, float_type *host_Hz, float_type *rf_Hz)
-	if limewrap.LMS_GetSampleRate(d.dev, limewrap.LmsChRx, 0, &host, &rf) != 0 {
-		panic(fmt.Sprintf("Failed to get SampleRate in %s at %s: %s", d.DeviceInfo.DeviceName, d.DeviceInfo.Media, limewrap.LMS_GetLastErrorMessage()))
+	if host, rf, err = d.backend.GetSampleRate(d.dev, true, 0); err != nil {
+		return 0, 0, d.deviceError("get sample rate", err, nil)
 	}
 
-	return host, rf
+	return d.fromHardwareFrequency(host), d.fromHardwareFrequency(rf), nil
 }
 
 // SetCenterFrequency sets the center frequency of the channel in Hertz.
-// Although two channels can have two different center frequencies, they share the same LO,
-// because of that some hardware tricks are done to be able to work at different frequencies
-// leading to a certain limit of how spaced these two channels can be.
-func (d *LMSDevice) SetCenterFrequency(channelNumber int, isRX bool, centerFrequency float64) {
+// Channels of the same direction share the same LO, so this also retunes the other channels of the direction
+// and disables their NCO, including the offsets set by SetCenterFrequencies.
+// Use SetCenterFrequencies to tune them to different center frequencies using the NCO of each channel,
+// which leads to a certain limit of how spaced these channels can be.
+func (d *LMSDevice) SetCenterFrequency(channelNumber int, isRX bool, centerFrequency float64) error {
 	runtime.LockOSThread()
 	defer runtime.UnlockOSThread()
-	if limewrap.LMS_SetLOFrequency(d.dev, !isRX, int64(channelNumber), centerFrequency) != 0 {
-		panic(fmt.Sprintf("Failed to set Frequency in %s at %s: %s", d.DeviceInfo.DeviceName, d.DeviceInfo.Media, limewrap.LMS_GetLastErrorMessage()))
+	if err := d.backend.SetLOFrequency(d.dev, isRX, channelNumber, d.toHardwareFrequency(centerFrequency)); err != nil {
+		return d.channelError("set frequency", channelNumber, isRX, err, nil)
 	}
+
+	for _, ch := range d.channels(isRX) {
+		if ch.ncoIndex >= 0 {
+			d.log(LogInfo, ch.parentIndex, isRX, nil, "NCO disabled by the center frequency change")
+		}
+		if err := d.setNCOIndex(ch, -1, false); err != nil {
+			return err
+		}
+	}
+
+	return d.retuned(isRX, centerFrequency)
 }
 
-// GetCenterFrequency gets the center frequency currently set in the channel.
-func (d *LMSDevice) GetCenterFrequency(channelNumber int, isRX bool) (centerFrequency float64) {
+// GetCenterFrequency gets the center frequency currently set in the channel, that is the LO frequency shifted by the NCO of the channel.
+func (d *LMSDevice) GetCenterFrequency(channelNumber int, isRX bool) (float64, error) {
 	runtime.LockOSThread()
 	defer runtime.UnlockOSThread()
-	if limewrap.LMS_GetLOFrequency(d.dev, !isRX, int64(channelNumber), &centerFrequency) != 0 {
-		panic(fmt.Sprintf("Failed to set Frequency in %s at %s: %s", d.DeviceInfo.DeviceName, d.DeviceInfo.Media, limewrap.LMS_GetLastErrorMessage()))
+	ch, err := d.getChannel(channelNumber, isRX)
+	if err != nil {
+		return 0, err
+	}
+
+	lo, err := d.backend.GetLOFrequency(d.dev, isRX, channelNumber)
+	if err != nil {
+		return 0, d.channelError("get frequency", channelNumber, isRX, err, nil)
+	}
+
+	offset, err := d.ncoOffset(ch)
+	if err != nil {
+		return 0, err
 	}
-	return centerFrequency
+	return d.fromHardwareFrequency(lo) + offset, nil
 }
 
 // Close closes the device connection with the hardware. This instance will be unusable after this call.
-func (d *LMSDevice) Close() {
-	Close(d)
+func (d *LMSDevice) Close() error {
+	return Close(d)
 }
 
 // String returns a string representing this device with information like name, channels, sample rate.
@@ -1,10 +1,5 @@
 package limedrv
 
-import (
-	"fmt"
-	"github.com/racerxdl/limedrv/limewrap"
-)
-
 // LMSDeviceAdvanced is a dummy structure just to separated the methods considered for "Advanced Usage"
 // It does not have any data besides the methods to allow advanced settings of LMSDevice object.
 type LMSDeviceAdvanced struct {
@@ -13,28 +8,36 @@ type LMSDeviceAdvanced struct {
 
 // SetDigitalFilterTaps allows to manually set the GFIR digital filter taps from a channel.
 // For enabling / disabling the GFIR when setting manual taps please use EnableGFIR / DisableGFIR in Advanced Section
-func (d *LMSDeviceAdvanced) SetDigitalFilterTaps(gFirIdx, channelNumber int, isRX bool, taps []float64) {
-	if limewrap.LMS_SetGFIRCoeff(d.parent.dev, !isRX, int64(channelNumber), limewrap.Lms_gfir_t(gFirIdx), &taps[0], int64(len(taps))) != 0 {
-		panic(fmt.Sprintf("Cannot set digital filter taps %s at %s: %s", d.parent.DeviceInfo.DeviceName, d.parent.DeviceInfo.Media, limewrap.LMS_GetLastErrorMessage()))
+func (d *LMSDeviceAdvanced) SetDigitalFilterTaps(gFirIdx, channelNumber int, isRX bool, taps []float64) error {
+	ch, err := d.parent.getChannel(channelNumber, isRX)
+	if err != nil {
+		return err
+	}
+
+	if len(taps) == 0 {
+		return d.parent.channelError("set digital filter taps", channelNumber, isRX, nil, ErrOutOfRange)
 	}
 
-	if isRX {
-		d.parent.RXChannels[channelNumber].advancedFiltering = true
-	} else {
-		d.parent.TXChannels[channelNumber].advancedFiltering = true
+	if err := d.parent.backend.SetGFIRCoeff(d.parent.dev, isRX, channelNumber, gFirIdx, taps); err != nil {
+		return d.parent.channelError("set digital filter taps", channelNumber, isRX, err, nil)
 	}
+
+	ch.advancedFiltering = true
+	return nil
 }
 
 // EnableGFIR enables a manually set GFIR Taps in the channel
-func (d *LMSDeviceAdvanced) EnableGFir(gFirIdx, channelNumber int, isRX bool) {
-	if limewrap.LMS_SetGFIR(d.parent.dev, !isRX, int64(channelNumber), limewrap.Lms_gfir_t(gFirIdx), true) != 0 {
-		panic(fmt.Sprintf("Cannot enable GFir %s at %s: %s", d.parent.DeviceInfo.DeviceName, d.parent.DeviceInfo.Media, limewrap.LMS_GetLastErrorMessage()))
+func (d *LMSDeviceAdvanced) EnableGFir(gFirIdx, channelNumber int, isRX bool) error {
+	if err := d.parent.backend.SetGFIR(d.parent.dev, isRX, channelNumber, gFirIdx, true); err != nil {
+		return d.parent.channelError("enable GFir", channelNumber, isRX, err, nil)
 	}
+	return nil
 }
 
 // DisableGFIR disables a manually set GFIR Taps in the channel
-func (d *LMSDeviceAdvanced) DisableGFir(gFirIdx, channelNumber int, isRX bool) {
-	if limewrap.LMS_SetGFIR(d.parent.dev, !isRX, int64(channelNumber), limewrap.Lms_gfir_t(gFirIdx), false) != 0 {
-		panic(fmt.Sprintf("Cannot disable GFir %s at %s: %s", d.parent.DeviceInfo.DeviceName, d.parent.DeviceInfo.Media, limewrap.LMS_GetLastErrorMessage()))
+func (d *LMSDeviceAdvanced) DisableGFir(gFirIdx, channelNumber int, isRX bool) error {
+	if err := d.parent.backend.SetGFIR(d.parent.dev, isRX, channelNumber, gFirIdx, false); err != nil {
+		return d.parent.channelError("disable GFir", channelNumber, isRX, err, nil)
 	}
+	return nil
 }
@@ -0,0 +1,238 @@
+package limedrv
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+)
+
+// LMS7Parameter is a bit field of a LMS7002M register, with the same name used by LimeSuite.
+// Parameters not in the map returned by LMS7Parameters can be accessed by building a LMS7Parameter with the address and bits
+// from the LMS7002M datasheet.
+type LMS7Parameter struct {
+	// Name is the name of the parameter. Example: G_LNA_RFE
+	Name string
+	// Address is the address of the register that contains the parameter
+	Address uint16
+	// MSB is the most significant bit of the parameter in the register
+	MSB uint8
+	// LSB is the least significant bit of the parameter in the register
+	LSB uint8
+	// Description is a short description of the parameter
+	Description string
+}
+
+// Mask returns the bits of the register used by the parameter
+func (p LMS7Parameter) Mask() uint16 {
+	return uint16((uint32(1)<<(p.MSB-p.LSB+1) - 1) << p.LSB)
+}
+
+// Extract returns the value of the parameter in a register value
+func (p LMS7Parameter) Extract(register uint16) uint16 {
+	return (register & p.Mask()) >> p.LSB
+}
+
+// Insert returns the register value with the parameter replaced by value. Bits of value that do not fit in the parameter are discarded.
+func (p LMS7Parameter) Insert(register, value uint16) uint16 {
+	return register&^p.Mask() | (value<<p.LSB)&p.Mask()
+}
+
+// String returns the name of the parameter and its position. Example: G_LNA_RFE (0x0113 [9:6])
+func (p LMS7Parameter) String() string {
+	return fmt.Sprintf("%s (0x%04X [%d:%d])", p.Name, p.Address, p.MSB, p.LSB)
+}
+
+// lms7Parameters is the map of the most used LMS7002M parameters.
+// Registers marked as MAC dependent are per channel, and the channel accessed is selected by MAC.
+var lms7Parameters = map[string]LMS7Parameter{}
+
+func init() {
+	for _, p := range []LMS7Parameter{
+		{"MAC", 0x0020, 1, 0, "Channel selection of MAC dependent registers: 1 - Channel A, 2 - Channel B, 3 - Both"},
+		{"TXEN_A", 0x0020, 2, 2, "Power control of TX channel A"},
+		{"TXEN_B", 0x0020, 3, 3, "Power control of TX channel B"},
+		{"RXEN_A", 0x0020, 4, 4, "Power control of RX channel A"},
+		{"RXEN_B", 0x0020, 5, 5, "Power control of RX channel B"},
+		{"SRST_TXFIFO", 0x0020, 6, 6, "TX FIFO soft reset (active low)"},
+		{"SRST_RXFIFO", 0x0020, 7, 7, "RX FIFO soft reset (active low)"},
+		{"MASK", 0x002F, 5, 0, "Chip mask revision (read only)"},
+		{"REV", 0x002F, 10, 6, "Chip revision (read only)"},
+		{"VER", 0x002F, 15, 11, "Chip version (read only)"},
+		{"FRAC_SDM_CGEN_LSB", 0x0087, 15, 0, "Fractional part of the CGEN divider, 16 least significant bits"},
+		{"FRAC_SDM_CGEN_MSB", 0x0088, 3, 0, "Fractional part of the CGEN divider, 4 most significant bits"},
+		{"INT_SDM_CGEN", 0x0088, 13, 4, "Integer part of the CGEN divider"},
+		{"DIV_OUTCH_CGEN", 0x0089, 10, 3, "CGEN output divider"},
+		{"VCO_CMPLO_CGEN", 0x008C, 12, 12, "CGEN VCO tuning voltage is above the low threshold (read only)"},
+		{"VCO_CMPHO_CGEN", 0x008C, 13, 13, "CGEN VCO tuning voltage is above the high threshold (read only)"},
+		{"SEL_BAND2_TRF", 0x0103, 10, 10, "Enables the TX band 2 output (MAC dependent)"},
+		{"SEL_BAND1_TRF", 0x0103, 11, 11, "Enables the TX band 1 output (MAC dependent)"},
+		{"SEL_PATH_RFE", 0x010D, 8, 7, "RX input path: 0 - None, 1 - LNAH, 2 - LNAL, 3 - LNAW (MAC dependent)"},
+		{"G_TIA_RFE", 0x0113, 1, 0, "RX TIA gain (MAC dependent)"},
+		{"G_LNA_RFE", 0x0113, 9, 6, "RX LNA gain (MAC dependent)"},
+		{"G_PGA_RBB", 0x0119, 4, 0, "RX PGA gain in dB (MAC dependent)"},
+		{"FRAC_SDM_LSB", 0x011D, 15, 0, "Fractional part of the SX divider, 16 least significant bits (MAC dependent: 1 - SXR, 2 - SXT)"},
+		{"FRAC_SDM_MSB", 0x011E, 3, 0, "Fractional part of the SX divider, 4 most significant bits (MAC dependent: 1 - SXR, 2 - SXT)"},
+		{"INT_SDM", 0x011E, 13, 4, "Integer part of the SX divider (MAC dependent: 1 - SXR, 2 - SXT)"},
+		{"SEL_VCO", 0x0121, 2, 1, "SX VCO selection: 0 - VCOL, 1 - VCOM, 2 - VCOH (MAC dependent: 1 - SXR, 2 - SXT)"},
+		{"CSW_VCO", 0x0121, 10, 3, "SX VCO capacitor bank (MAC dependent: 1 - SXR, 2 - SXT)"},
+		{"VCO_CMPLO", 0x0123, 12, 12, "SX VCO tuning voltage is above the low threshold (read only, MAC dependent: 1 - SXR, 2 - SXT)"},
+		{"VCO_CMPHO", 0x0123, 13, 13, "SX VCO tuning voltage is above the high threshold (read only, MAC dependent: 1 - SXR, 2 - SXT)"},
+		{"HBI_OVR_TXTSP", 0x0203, 14, 12, "TX interpolation ratio: 2^(N+1), 7 bypasses the interpolation (MAC dependent)"},
+		{"HBD_OVR_RXTSP", 0x0403, 14, 12, "RX decimation ratio: 2^(N+1), 7 bypasses the decimation (MAC dependent)"},
+	} {
+		lms7Parameters[p.Name] = p
+	}
+}
+
+// LookupLMS7Parameter returns the parameter of the register map with the specified LimeSuite name
+func LookupLMS7Parameter(name string) (LMS7Parameter, bool) {
+	p, ok := lms7Parameters[name]
+	return p, ok
+}
+
+// LMS7Parameters returns all parameters of the register map sorted by address and bit
+func LMS7Parameters() []LMS7Parameter {
+	var params = make([]LMS7Parameter, 0, len(lms7Parameters))
+	for _, p := range lms7Parameters {
+		params = append(params, p)
+	}
+	sort.Slice(params, func(i, j int) bool {
+		if params[i].Address != params[j].Address {
+			return params[i].Address < params[j].Address
+		}
+		return params[i].LSB < params[j].LSB
+	})
+	return params
+}
+
+// lms7RegisterRanges are the address ranges of the LMS7002M registers, used to dump them
+var lms7RegisterRanges = [][2]uint16{
+	{0x0020, 0x002F}, // LimeLight and top level
+	{0x0081, 0x00AE}, // AFE, BIAS, XBUF, CGEN, LDO, BIST and CDS
+	{0x0100, 0x0124}, // TRF, TBB, RFE, RBB and SX
+	{0x0200, 0x020C}, // TxTSP
+	{0x0240, 0x0261}, // TxNCO
+	{0x0400, 0x040F}, // RxTSP
+	{0x0440, 0x0461}, // RxNCO
+}
+
+// ReadLMSRegister returns the value of a register of the LMS7 chip.
+func (d *LMSDeviceAdvanced) ReadLMSRegister(address uint16) (uint16, error) {
+	value, err := d.parent.backend.ReadLMSReg(d.parent.dev, address)
+	if err != nil {
+		return 0, d.parent.deviceError(fmt.Sprintf("read LMS register 0x%04X", address), err, nil)
+	}
+	return value, nil
+}
+
+// WriteLMSRegister sets the value of a register of the LMS7 chip.
+// Writing registers directly can leave the device in a state that LimeSuite does not know about.
+func (d *LMSDeviceAdvanced) WriteLMSRegister(address, value uint16) error {
+	if err := d.parent.backend.WriteLMSReg(d.parent.dev, address, value); err != nil {
+		return d.parent.deviceError(fmt.Sprintf("write LMS register 0x%04X", address), err, nil)
+	}
+	return nil
+}
+
+// ReadFPGARegister returns the value of a register of the board FPGA.
+func (d *LMSDeviceAdvanced) ReadFPGARegister(address uint16) (uint16, error) {
+	value, err := d.parent.backend.ReadFPGAReg(d.parent.dev, address)
+	if err != nil {
+		return 0, d.parent.deviceError(fmt.Sprintf("read FPGA register 0x%04X", address), err, nil)
+	}
+	return value, nil
+}
+
+// WriteFPGARegister sets the value of a register of the board FPGA.
+func (d *LMSDeviceAdvanced) WriteFPGARegister(address, value uint16) error {
+	if err := d.parent.backend.WriteFPGAReg(d.parent.dev, address, value); err != nil {
+		return d.parent.deviceError(fmt.Sprintf("write FPGA register 0x%04X", address), err, nil)
+	}
+	return nil
+}
+
+// SynchronizeRegisters synchronizes the register values cached by LimeSuite with the LMS7 chip.
+// If toChip is true the cached values are written to the chip, otherwise the cache is updated with the values read from the chip.
+// It should be called after changing registers directly when LimeSuite calls rely on them.
+func (d *LMSDeviceAdvanced) SynchronizeRegisters(toChip bool) error {
+	if err := d.parent.backend.Synchronize(d.parent.dev, toChip); err != nil {
+		return d.parent.deviceError("synchronize registers", err, nil)
+	}
+	return nil
+}
+
+// ReadParameter returns the value of a LMS7 parameter.
+// Like LMS_ReadParam, it reads the register and extracts the bits (limewrap can not build the LimeSuite parameter struct).
+// MAC dependent parameters are read from the channel currently selected by the MAC parameter.
+func (d *LMSDeviceAdvanced) ReadParameter(p LMS7Parameter) (uint16, error) {
+	if p.MSB < p.LSB || p.MSB > 15 {
+		return 0, d.parent.deviceError(fmt.Sprintf("read parameter %s", p), nil, ErrOutOfRange)
+	}
+
+	register, err := d.ReadLMSRegister(p.Address)
+	if err != nil {
+		return 0, err
+	}
+	return p.Extract(register), nil
+}
+
+// WriteParameter sets the value of a LMS7 parameter, keeping the other bits of the register.
+// MAC dependent parameters are written in the channel currently selected by the MAC parameter.
+func (d *LMSDeviceAdvanced) WriteParameter(p LMS7Parameter, value uint16) error {
+	if p.MSB < p.LSB || p.MSB > 15 || p.Extract(p.Insert(0, value)) != value {
+		return d.parent.deviceError(fmt.Sprintf("write %d to parameter %s", value, p), nil, ErrOutOfRange)
+	}
+
+	register, err := d.ReadLMSRegister(p.Address)
+	if err != nil {
+		return err
+	}
+	return d.WriteLMSRegister(p.Address, p.Insert(register, value))
+}
+
+// DumpLMSRegisters writes the value of all LMS7 registers to w, one register per line as 0xADDRESS=0xVALUE.
+// MAC dependent registers are the ones of the channel currently selected by the MAC parameter.
+func (d *LMSDeviceAdvanced) DumpLMSRegisters(w io.Writer) error {
+	for _, r := range lms7RegisterRanges {
+		if err := d.dumpRegisters(w, r[0], r[1], d.ReadLMSRegister); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// DumpFPGARegisters writes the value of the FPGA registers from first to last address to w, one register per line as 0xADDRESS=0xVALUE.
+func (d *LMSDeviceAdvanced) DumpFPGARegisters(w io.Writer, first, last uint16) error {
+	return d.dumpRegisters(w, first, last, d.ReadFPGARegister)
+}
+
+// DumpLMSRegistersToFile writes the value of all LMS7 registers to a file. See DumpLMSRegisters.
+func (d *LMSDeviceAdvanced) DumpLMSRegistersToFile(filename string) error {
+	f, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+
+	if err := d.DumpLMSRegisters(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// dumpRegisters writes the registers from first to last address read by read to w
+func (d *LMSDeviceAdvanced) dumpRegisters(w io.Writer, first, last uint16, read func(uint16) (uint16, error)) error {
+	var bw = bufio.NewWriter(w)
+	for address := uint32(first); address <= uint32(last); address++ {
+		value, err := read(uint16(address))
+		if err != nil {
+			return err
+		}
+		if _, err := fmt.Fprintf(bw, "0x%04X=0x%04X\n", address, value); err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
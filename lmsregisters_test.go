@@ -0,0 +1,47 @@
+package limedrv
+
+import "testing"
+
+func TestLMS7Parameters(t *testing.T) {
+	// Positions from the LMS7002M parameter list of LimeSuite (LMS7002M_parameters.h)
+	var tests = []struct {
+		name     string
+		address  uint16
+		msb, lsb uint8
+	}{
+		{"MAC", 0x0020, 1, 0},
+		{"TXEN_A", 0x0020, 2, 2},
+		{"TXEN_B", 0x0020, 3, 3},
+		{"RXEN_A", 0x0020, 4, 4},
+		{"RXEN_B", 0x0020, 5, 5},
+		{"SRST_TXFIFO", 0x0020, 6, 6},
+		{"SRST_RXFIFO", 0x0020, 7, 7},
+		{"VER", 0x002F, 15, 11},
+		{"INT_SDM_CGEN", 0x0088, 13, 4},
+		{"SEL_PATH_RFE", 0x010D, 8, 7},
+		{"G_LNA_RFE", 0x0113, 9, 6},
+		{"G_PGA_RBB", 0x0119, 4, 0},
+		{"CSW_VCO", 0x0121, 10, 3},
+		{"HBD_OVR_RXTSP", 0x0403, 14, 12},
+	}
+
+	for _, tt := range tests {
+		p, ok := LookupLMS7Parameter(tt.name)
+		if !ok {
+			t.Errorf("%s is not in the register map", tt.name)
+			continue
+		}
+		if p.Address != tt.address || p.MSB != tt.msb || p.LSB != tt.lsb {
+			t.Errorf("%s is %s, want 0x%04X [%d:%d]", tt.name, p, tt.address, tt.msb, tt.lsb)
+		}
+	}
+
+	// Parameters of the same register should not overlap
+	var used = map[uint16]uint16{}
+	for _, p := range LMS7Parameters() {
+		if used[p.Address]&p.Mask() != 0 {
+			t.Errorf("%s overlaps another parameter of register 0x%04X", p, p.Address)
+		}
+		used[p.Address] |= p.Mask()
+	}
+}
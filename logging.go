@@ -0,0 +1,136 @@
+package limedrv
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// LogLevel is the severity of a log entry
+type LogLevel int
+
+const (
+	// LogError is a failure that the caller will probably see as a error
+	LogError LogLevel = iota
+	// LogWarning is a problem that limedrv or LimeSuite could recover from, like a stream error or a TX underrun
+	LogWarning
+	// LogInfo is a normal event, like opening a device or starting the streams
+	LogInfo
+	// LogDebug is a detailed event only useful while debugging
+	LogDebug
+)
+
+var logLevelNames = map[LogLevel]string{
+	LogError:   "ERROR",
+	LogWarning: "WARNING",
+	LogInfo:    "INFO",
+	LogDebug:   "DEBUG",
+}
+
+// String returns the name of the log level
+func (l LogLevel) String() string {
+	if name, ok := logLevelNames[l]; ok {
+		return name
+	}
+	return fmt.Sprintf("LogLevel(%d)", int(l))
+}
+
+const (
+	// LogSourceLimeSuite is the source of the log entries written by LimeSuite
+	LogSourceLimeSuite = "limesuite"
+	// LogSourceLimedrv is the source of the log entries written by limedrv
+	LogSourceLimedrv = "limedrv"
+)
+
+// LogEntry is a log message from LimeSuite or limedrv
+type LogEntry struct {
+	Level LogLevel
+	// Source is LogSourceLimeSuite or LogSourceLimedrv
+	Source  string
+	Message string
+	// DeviceName is the name of the device of the event. LimeSuite messages do not tell the device, so it is empty for them.
+	DeviceName string
+	// Channel is the channel number of the event or -1 if it is not about a channel
+	Channel int
+	// IsRX is the direction of the channel. Only meaningful if Channel is not -1
+	IsRX bool
+	// Err is the error that caused the event, if any
+	Err error
+}
+
+// Logger receives the log entries of LimeSuite and limedrv. See SetLogger.
+type Logger interface {
+	Log(entry LogEntry)
+}
+
+// LoggerFunc is a function that implements Logger
+type LoggerFunc func(entry LogEntry)
+
+// Log calls f(entry)
+func (f LoggerFunc) Log(entry LogEntry) {
+	f(entry)
+}
+
+var (
+	loggerMtx sync.RWMutex
+	logger    Logger
+)
+
+// SetLogger sets the Logger that receives the messages of LimeSuite and the events of limedrv, in all backends.
+// Log calls can come from any goroutine, including the stream loops, so the Logger should be safe for concurrent use and should not block.
+// A nil Logger disables limedrv logging and makes LimeSuite write its messages to stderr again.
+func SetLogger(l Logger) {
+	loggerMtx.Lock()
+	logger = l
+	loggerMtx.Unlock()
+
+	for _, backend := range allBackends() {
+		applyLogger(backend)
+	}
+}
+
+func currentLogger() Logger {
+	loggerMtx.RLock()
+	defer loggerMtx.RUnlock()
+	return logger
+}
+
+// applyLogger routes the backend messages to the current Logger, or restores the backend default if there is none
+func applyLogger(backend Backend) {
+	if currentLogger() != nil {
+		backend.SetLogHandler(logBackendMessage)
+	} else {
+		backend.SetLogHandler(nil)
+	}
+}
+
+// logBackendMessage sends a message reported by a backend to the current Logger
+func logBackendMessage(level LogLevel, message string) {
+	if l := currentLogger(); l != nil {
+		l.Log(LogEntry{
+			Level:   level,
+			Source:  LogSourceLimeSuite,
+			Message: strings.TrimSpace(message),
+			Channel: -1,
+		})
+	}
+}
+
+// log sends a limedrv event of the device to the current Logger.
+// channelNumber is -1 for device wide events. The message is only formatted if there is a Logger.
+func (d *LMSDevice) log(level LogLevel, channelNumber int, isRX bool, err error, format string, args ...interface{}) {
+	var l = currentLogger()
+	if l == nil {
+		return
+	}
+
+	l.Log(LogEntry{
+		Level:      level,
+		Source:     LogSourceLimedrv,
+		Message:    fmt.Sprintf(format, args...),
+		DeviceName: d.DeviceInfo.DeviceName,
+		Channel:    channelNumber,
+		IsRX:       isRX,
+		Err:        err,
+	})
+}
@@ -0,0 +1,57 @@
+package limedrv
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestLogger(t *testing.T) {
+	var mtx sync.Mutex
+	var entries []LogEntry
+	SetLogger(LoggerFunc(func(entry LogEntry) {
+		mtx.Lock()
+		entries = append(entries, entry)
+		mtx.Unlock()
+	}))
+	defer SetLogger(nil)
+
+	var d = openSim(t, 100e6)
+	defer d.Close()
+	if err := d.Calibrate(1, true, 5e6); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Calibrate(0, false, 5e6); err != nil {
+		t.Fatal(err)
+	}
+
+	mtx.Lock()
+	var got = append([]LogEntry{}, entries...)
+	mtx.Unlock()
+
+	var expected = []struct {
+		channel int
+		isRX    bool
+	}{{-1, false}, {1, true}, {0, false}}
+	if len(got) != len(expected) {
+		t.Fatalf("expected %d log entries (open and two calibrations), got %+v", len(expected), got)
+	}
+	for i, e := range expected {
+		var entry = got[i]
+		if entry.Source != LogSourceLimedrv || entry.Level != LogInfo || entry.DeviceName != d.DeviceInfo.DeviceName {
+			t.Errorf("entry %d: expected a limedrv info entry of %s, got %+v", i, d.DeviceInfo.DeviceName, entry)
+		}
+		if entry.Channel != e.channel || (e.channel >= 0 && entry.IsRX != e.isRX) {
+			t.Errorf("entry %d: expected channel %d with IsRX %t, got %+v", i, e.channel, e.isRX, entry)
+		}
+	}
+
+	SetLogger(nil)
+	if err := d.Calibrate(0, true, 5e6); err != nil {
+		t.Fatal(err)
+	}
+	mtx.Lock()
+	defer mtx.Unlock()
+	if len(entries) != len(got) {
+		t.Errorf("expected no entries after SetLogger(nil), got %+v", entries[len(got):])
+	}
+}
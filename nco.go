@@ -0,0 +1,225 @@
+package limedrv
+
+import (
+	"fmt"
+	"runtime"
+)
+
+// setNCOIndex selects the NCO table entry of the channel, or disables the NCO if index is -1, and keeps it for ncoOffset
+func (d *LMSDevice) setNCOIndex(ch *LMSChannel, index int, downConvert bool) error {
+	if err := d.backend.SetNCOIndex(d.dev, ch.IsRX, ch.parentIndex, index, downConvert); err != nil {
+		if index < 0 {
+			return d.channelError("disable NCO", ch.parentIndex, ch.IsRX, err, nil)
+		}
+		return d.channelError("set NCO index", ch.parentIndex, ch.IsRX, err, nil)
+	}
+	ch.ncoIndex = index
+	ch.ncoDownConvert = downConvert
+	return nil
+}
+
+// ncoOffset returns how much the NCO of the channel shifts its center frequency from the LO in Hertz, or 0 if the NCO is disabled.
+// The NCO state is the one set through limedrv, since LimeSuite does not report the conversion direction.
+func (d *LMSDevice) ncoOffset(ch *LMSChannel) (float64, error) {
+	if ch.ncoIndex < 0 {
+		return 0, nil
+	}
+
+	var shift float64
+	if ch.ncoPhaseMode {
+		_, frequency, err := d.backend.GetNCOPhase(d.dev, ch.IsRX, ch.parentIndex)
+		if err != nil {
+			return 0, d.channelError("get NCO phases", ch.parentIndex, ch.IsRX, err, nil)
+		}
+		shift = frequency
+	} else {
+		frequencies, _, err := d.backend.GetNCOFrequency(d.dev, ch.IsRX, ch.parentIndex)
+		if err != nil {
+			return 0, d.channelError("get NCO frequencies", ch.parentIndex, ch.IsRX, err, nil)
+		}
+		if ch.ncoIndex >= len(frequencies) {
+			return 0, d.channelError(fmt.Sprintf("get NCO frequency %d", ch.ncoIndex), ch.parentIndex, ch.IsRX, nil, ErrOutOfRange)
+		}
+		shift = frequencies[ch.ncoIndex]
+	}
+
+	// RX channels are tuned above the LO by down converting, TX channels by up converting
+	shift = d.fromHardwareFrequency(shift)
+	if ch.ncoDownConvert != ch.IsRX {
+		return -shift, nil
+	}
+	return shift, nil
+}
+
+// checkNCOTable returns a ErrOutOfRange error if the table does not fit in the NCO
+func (d *LMSDevice) checkNCOTable(op string, channelNumber int, isRX bool, table []float64) error {
+	if len(table) == 0 || len(table) > NCOValueCount {
+		return d.channelError(fmt.Sprintf("%s with %d values", op, len(table)), channelNumber, isRX, nil, ErrOutOfRange)
+	}
+	return nil
+}
+
+// SetNCOFrequencies loads the NCO frequency table of the specified channel with up to NCOValueCount frequencies in Hertz.
+// The remaining entries are set to 0. phaseOffset is the NCO phase in degrees.
+// The frequency in use is selected by SetNCOIndex, so switching between them does not move the LO.
+func (d *LMSDevice) SetNCOFrequencies(channelNumber int, isRX bool, frequencies []float64, phaseOffset float64) error {
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+	if err := d.checkNCOTable("set NCO frequencies", channelNumber, isRX, frequencies); err != nil {
+		return err
+	}
+	ch, err := d.getChannel(channelNumber, isRX)
+	if err != nil {
+		return err
+	}
+
+	var table = make([]float64, NCOValueCount)
+	copy(table, frequencies)
+	if err := d.backend.SetNCOFrequency(d.dev, isRX, channelNumber, table, phaseOffset); err != nil {
+		return d.channelError("set NCO frequencies", channelNumber, isRX, err, nil)
+	}
+	ch.ncoPhaseMode = false
+	return nil
+}
+
+// GetNCOFrequencies returns the NCO frequency table of the specified channel in Hertz and the NCO phase offset in degrees.
+func (d *LMSDevice) GetNCOFrequencies(channelNumber int, isRX bool) (frequencies []float64, phaseOffset float64, err error) {
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+	if frequencies, phaseOffset, err = d.backend.GetNCOFrequency(d.dev, isRX, channelNumber); err != nil {
+		return nil, 0, d.channelError("get NCO frequencies", channelNumber, isRX, err, nil)
+	}
+	return frequencies, phaseOffset, nil
+}
+
+// SetNCOPhases loads the NCO phase table of the specified channel with up to NCOValueCount phases in degrees.
+// The remaining entries are set to 0. frequency is the NCO frequency in Hertz.
+// The phase in use is selected by SetNCOIndex.
+func (d *LMSDevice) SetNCOPhases(channelNumber int, isRX bool, phases []float64, frequency float64) error {
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+	if err := d.checkNCOTable("set NCO phases", channelNumber, isRX, phases); err != nil {
+		return err
+	}
+	ch, err := d.getChannel(channelNumber, isRX)
+	if err != nil {
+		return err
+	}
+
+	var table = make([]float64, NCOValueCount)
+	copy(table, phases)
+	if err := d.backend.SetNCOPhase(d.dev, isRX, channelNumber, table, frequency); err != nil {
+		return d.channelError("set NCO phases", channelNumber, isRX, err, nil)
+	}
+	ch.ncoPhaseMode = true
+	return nil
+}
+
+// GetNCOPhases returns the NCO phase table of the specified channel in degrees and the NCO frequency in Hertz.
+func (d *LMSDevice) GetNCOPhases(channelNumber int, isRX bool) (phases []float64, frequency float64, err error) {
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+	if phases, frequency, err = d.backend.GetNCOPhase(d.dev, isRX, channelNumber); err != nil {
+		return nil, 0, d.channelError("get NCO phases", channelNumber, isRX, err, nil)
+	}
+	return phases, frequency, nil
+}
+
+// SetNCOIndex selects the entry of the NCO table used by the specified channel and enables the NCO.
+// downConvert selects the direction of the frequency shift: down conversion if true, up conversion otherwise.
+// RX channels are tuned above the LO by down converting and TX channels by up converting.
+// SetCenterFrequency disables the NCO of all channels of the direction, so call it before selecting the NCO entry.
+func (d *LMSDevice) SetNCOIndex(channelNumber int, isRX bool, index int, downConvert bool) error {
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+	if index < 0 || index >= NCOValueCount {
+		return d.channelError(fmt.Sprintf("set NCO index to %d", index), channelNumber, isRX, nil, ErrOutOfRange)
+	}
+
+	ch, err := d.getChannel(channelNumber, isRX)
+	if err != nil {
+		return err
+	}
+	return d.setNCOIndex(ch, index, downConvert)
+}
+
+// DisableNCO disables the NCO of the specified channel
+func (d *LMSDevice) DisableNCO(channelNumber int, isRX bool) error {
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+	ch, err := d.getChannel(channelNumber, isRX)
+	if err != nil {
+		return err
+	}
+	return d.setNCOIndex(ch, -1, false)
+}
+
+// GetNCOIndex returns the entry of the NCO table used by the specified channel.
+// LimeSuite reports an error if the NCO is disabled.
+func (d *LMSDevice) GetNCOIndex(channelNumber int, isRX bool) (index int, err error) {
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+	if index, err = d.backend.GetNCOIndex(d.dev, isRX, channelNumber); err != nil {
+		return 0, d.channelError("get NCO index", channelNumber, isRX, err, nil)
+	}
+	return index, nil
+}
+
+// GetNCOFrequency returns the frequency in Hertz of the NCO table entry used by the specified channel
+func (d *LMSDevice) GetNCOFrequency(channelNumber int, isRX bool) (float64, error) {
+	index, err := d.GetNCOIndex(channelNumber, isRX)
+	if err != nil {
+		return 0, err
+	}
+
+	frequencies, _, err := d.GetNCOFrequencies(channelNumber, isRX)
+	if err != nil {
+		return 0, err
+	}
+
+	if index >= len(frequencies) {
+		return 0, d.channelError(fmt.Sprintf("get NCO frequency %d", index), channelNumber, isRX, nil, ErrOutOfRange)
+	}
+
+	return frequencies[index], nil
+}
+
+// SetNCOFrequencies loads the NCO frequency table of the current channel with up to NCOValueCount frequencies in hertz.
+func (c *LMSChannel) SetNCOFrequencies(frequencies []float64, phaseOffset float64) error {
+	return c.parent.SetNCOFrequencies(c.parentIndex, c.IsRX, frequencies, phaseOffset)
+}
+
+// GetNCOFrequencies returns the NCO frequency table of the current channel in hertz and the phase offset in degrees.
+func (c *LMSChannel) GetNCOFrequencies() ([]float64, float64, error) {
+	return c.parent.GetNCOFrequencies(c.parentIndex, c.IsRX)
+}
+
+// SetNCOPhases loads the NCO phase table of the current channel with up to NCOValueCount phases in degrees.
+func (c *LMSChannel) SetNCOPhases(phases []float64, frequency float64) error {
+	return c.parent.SetNCOPhases(c.parentIndex, c.IsRX, phases, frequency)
+}
+
+// GetNCOPhases returns the NCO phase table of the current channel in degrees and the frequency in hertz.
+func (c *LMSChannel) GetNCOPhases() ([]float64, float64, error) {
+	return c.parent.GetNCOPhases(c.parentIndex, c.IsRX)
+}
+
+// SetNCOIndex selects the NCO table entry of the current channel and the conversion direction.
+func (c *LMSChannel) SetNCOIndex(index int, downConvert bool) error {
+	return c.parent.SetNCOIndex(c.parentIndex, c.IsRX, index, downConvert)
+}
+
+// DisableNCO disables the NCO of the current channel.
+func (c *LMSChannel) DisableNCO() error {
+	return c.parent.DisableNCO(c.parentIndex, c.IsRX)
+}
+
+// GetNCOIndex returns the NCO table entry used by the current channel.
+func (c *LMSChannel) GetNCOIndex() (int, error) {
+	return c.parent.GetNCOIndex(c.parentIndex, c.IsRX)
+}
+
+// GetNCOFrequency returns the NCO frequency in hertz used by the current channel.
+func (c *LMSChannel) GetNCOFrequency() (float64, error) {
+	return c.parent.GetNCOFrequency(c.parentIndex, c.IsRX)
+}
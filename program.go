@@ -0,0 +1,48 @@
+package limedrv
+
+import (
+	"fmt"
+	"runtime"
+)
+
+// GetProgramModes returns the program modes supported by the device, for example "FPGA FLASH" or "FX3 FLASH" in a LimeSDR USB.
+func (d *LMSDevice) GetProgramModes() ([]string, error) {
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+	modes, err := d.backend.GetProgramModes(d.dev)
+	if err != nil {
+		return nil, d.deviceError("get program modes", err, nil)
+	}
+	return modes, nil
+}
+
+// Program writes a firmware or gateware image to the device using one of the modes returned by GetProgramModes.
+// Modes that only reset a part of the device do not need a image.
+// progress is optional and is called during the programming with the bytes written and the total bytes.
+// Returning false from progress aborts the programming, and Program returns a ErrAborted error.
+// The device should not be running, and usually needs to be opened again after the programming.
+func (d *LMSDevice) Program(mode string, image []byte, progress func(done, total int) bool) error {
+	modes, err := d.GetProgramModes()
+	if err != nil {
+		return err
+	}
+
+	var found = false
+	for _, m := range modes {
+		found = found || m == mode
+	}
+	if !found {
+		return d.deviceError(fmt.Sprintf("program with mode %q", mode), nil, ErrOutOfRange)
+	}
+
+	if d.running {
+		return d.deviceError("program", nil, ErrAlreadyRunning)
+	}
+
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+	if err := d.backend.Program(d.dev, mode, image, progress); err != nil {
+		return d.deviceError(fmt.Sprintf("program %d bytes with mode %q", len(image), mode), err, nil)
+	}
+	return nil
+}
@@ -0,0 +1,56 @@
+package limedrv
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestProgram(t *testing.T) {
+	var d = openSim(t, 100e6)
+	defer d.Close()
+	var image = make([]byte, 3*simProgramChunk+1)
+
+	var reports [][2]int
+	err := d.Program("FPGA FLASH", image, func(done, total int) bool {
+		reports = append(reports, [2]int{done, total})
+		return true
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(reports) != 5 || reports[len(reports)-1] != [2]int{len(image), len(image)} {
+		t.Errorf("expected 5 progress reports ending at %d bytes, got %v", len(image), reports)
+	}
+	for i := 1; i < len(reports); i++ {
+		if reports[i][0] <= reports[i-1][0] {
+			t.Errorf("expected increasing progress, got %v", reports)
+			break
+		}
+	}
+}
+
+func TestProgramAbort(t *testing.T) {
+	var d = openSim(t, 100e6)
+	defer d.Close()
+
+	var calls = 0
+	err := d.Program("FPGA FLASH", make([]byte, 3*simProgramChunk), func(done, total int) bool {
+		calls++
+		return calls < 2
+	})
+	if !errors.Is(err, ErrAborted) {
+		t.Errorf("expected ErrAborted, got %v", err)
+	}
+	if calls != 2 {
+		t.Errorf("expected programming to stop at the first false progress, got %d calls", calls)
+	}
+}
+
+func TestProgramUnknownMode(t *testing.T) {
+	var d = openSim(t, 100e6)
+	defer d.Close()
+
+	if err := d.Program("EEPROM", nil, nil); !errors.Is(err, ErrOutOfRange) {
+		t.Errorf("expected ErrOutOfRange for a unknown mode, got %v", err)
+	}
+}
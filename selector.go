@@ -0,0 +1,117 @@
+package limedrv
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// DeviceSelector selects devices by the value of their DeviceInfo fields. See ParseDeviceSelector.
+type DeviceSelector map[string]string
+
+// selectorFields returns the value of each DeviceSelector key in a DeviceInfo
+func selectorFields(d DeviceInfo) map[string]string {
+	return map[string]string{
+		"name":   d.DeviceName,
+		"media":  d.Media,
+		"module": d.Module,
+		"addr":   d.Addr,
+		"serial": d.Serial,
+	}
+}
+
+// ParseDeviceSelector parses a selector with comma separated key=value pairs, like "serial=0009060B00471B22,media=USB 3.0".
+// The keys are name, media, module, addr and serial.
+// A single part without a key is the device name, so the device strings of LimeSuite (like "LimeSDR Mini, media=USB 3.0, serial=1D3AC7A1E3B5F4")
+// are also valid selectors. Their index key is ignored, since it is only meaningful in the LimeSuite device list.
+func ParseDeviceSelector(selector string) (DeviceSelector, error) {
+	var keys = selectorFields(DeviceInfo{})
+	var sel = DeviceSelector{}
+	var name string
+
+	for _, part := range strings.Split(selector, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		var kv = strings.SplitN(part, "=", 2)
+		if len(kv) == 1 {
+			if name != "" {
+				return nil, fmt.Errorf("limedrv: device selector %q has two parts without a key (%q and %q)", selector, name, part)
+			}
+			name = part
+			sel["name"] = part
+			continue
+		}
+
+		var key = strings.ToLower(strings.TrimSpace(kv[0]))
+		if key == "index" {
+			continue
+		}
+		if _, ok := keys[key]; !ok {
+			return nil, fmt.Errorf("limedrv: unknown key %q in device selector %q", key, selector)
+		}
+		sel[key] = strings.TrimSpace(kv[1])
+	}
+
+	return sel, nil
+}
+
+// Match returns true if the device has all the values of the selector. Values are compared ignoring case,
+// and serial numbers are compared as numbers, so leading zeros are optional.
+func (sel DeviceSelector) Match(device DeviceInfo) bool {
+	var fields = selectorFields(device)
+	for key, value := range sel {
+		if key == "serial" && device.BoardSerialNumber != 0 {
+			if serial, err := strconv.ParseUint(value, 16, 64); err == nil {
+				if serial != device.BoardSerialNumber {
+					return false
+				}
+				continue
+			}
+		}
+		if !strings.EqualFold(fields[key], value) {
+			return false
+		}
+	}
+	return true
+}
+
+// FindDevices returns the devices returned by GetDevices that match the selector. See ParseDeviceSelector.
+func FindDevices(selector string) ([]DeviceInfo, error) {
+	sel, err := ParseDeviceSelector(selector)
+	if err != nil {
+		return nil, err
+	}
+
+	var ret = make([]DeviceInfo, 0)
+	for _, device := range GetDevices() {
+		if sel.Match(device) {
+			ret = append(ret, device)
+		}
+	}
+	return ret, nil
+}
+
+// OpenBySelector opens the only device returned by GetDevices that matches the selector. See ParseDeviceSelector.
+// It returns a ErrDeviceNotFound error if no device matches and a ErrMultipleDevices error if more than one does.
+func OpenBySelector(selector string) (*LMSDevice, error) {
+	devices, err := FindDevices(selector)
+	if err != nil {
+		return nil, err
+	}
+
+	switch len(devices) {
+	case 0:
+		return nil, fmt.Errorf("limedrv: no device matches %q: %w", selector, ErrDeviceNotFound)
+	case 1:
+		return Open(devices[0])
+	}
+
+	var names = make([]string, len(devices))
+	for i, device := range devices {
+		names[i] = fmt.Sprintf("%s at %s (serial %s)", device.DeviceName, device.Media, device.Serial)
+	}
+	return nil, fmt.Errorf("limedrv: %d devices match %q: %s: %w", len(devices), selector, strings.Join(names, ", "), ErrMultipleDevices)
+}
@@ -0,0 +1,122 @@
+package limedrv
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestParseDeviceSelector(t *testing.T) {
+	var tests = []struct {
+		selector string
+		expected DeviceSelector
+		fails    bool
+	}{
+		{"", DeviceSelector{}, false},
+		{"serial=0009060B00471B22,media=USB 3.0", DeviceSelector{"serial": "0009060B00471B22", "media": "USB 3.0"}, false},
+		{"LimeSDR-USB, media=USB 3.0, module=FX3, addr=1d50:6108, serial=0009060B00471B22, index=0",
+			DeviceSelector{"name": "LimeSDR-USB", "media": "USB 3.0", "module": "FX3", "addr": "1d50:6108", "serial": "0009060B00471B22"}, false},
+		{"index=1", DeviceSelector{}, false},
+		{"Serial=9060B00471B22, MEDIA=USB 3.0", DeviceSelector{"serial": "9060B00471B22", "media": "USB 3.0"}, false},
+		{"LimeSDR Mini", DeviceSelector{"name": "LimeSDR Mini"}, false},
+		{"firmware=4", nil, true},
+		{"serial=0009060B00471B22, color=blue", nil, true},
+		{"LimeSDR-USB, LimeSDR Mini", nil, true},
+	}
+
+	for _, tt := range tests {
+		sel, err := ParseDeviceSelector(tt.selector)
+		if tt.fails {
+			if err == nil {
+				t.Errorf("%q: expected an error, got %v", tt.selector, sel)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q: %v", tt.selector, err)
+			continue
+		}
+		if !reflect.DeepEqual(sel, tt.expected) {
+			t.Errorf("%q: expected %v, got %v", tt.selector, tt.expected, sel)
+		}
+	}
+}
+
+func TestDeviceSelectorMatch(t *testing.T) {
+	var device = DeviceInfo{
+		DeviceName:        "LimeSDR-USB",
+		Media:             "USB 3.0",
+		Module:            "FX3",
+		Addr:              "1d50:6108",
+		Serial:            "0009060B00471B22",
+		BoardSerialNumber: 0x0009060B00471B22,
+	}
+
+	var tests = []struct {
+		selector string
+		matches  bool
+	}{
+		{"", true},
+		{"LimeSDR-USB, media=USB 3.0, module=FX3, addr=1d50:6108, serial=0009060B00471B22, index=3", true},
+		{"serial=0009060B00471B22", true},
+		{"serial=9060B00471B22", true},
+		{"serial=0009060b00471b22", true},
+		{"serial=0009060B00471B23", false},
+		{"name=limesdr-usb,media=usb 3.0", true},
+		{"LIMESDR-USB", true},
+		{"LimeSDR Mini", false},
+		{"media=USB 2.0", false},
+		{"module=FX3, addr=1d50:6109", false},
+	}
+
+	for _, tt := range tests {
+		sel, err := ParseDeviceSelector(tt.selector)
+		if err != nil {
+			t.Errorf("%q: %v", tt.selector, err)
+			continue
+		}
+		if sel.Match(device) != tt.matches {
+			t.Errorf("%q: expected match %t", tt.selector, tt.matches)
+		}
+	}
+
+	// Serials that are not numbers are compared as text
+	var sim = DeviceInfo{Serial: "SIM0000000000001", BoardSerialNumber: 1}
+	for selector, matches := range map[string]bool{"serial=sim0000000000001": true, "serial=1": true, "serial=SIM1": false} {
+		if sel, _ := ParseDeviceSelector(selector); sel.Match(sim) != matches {
+			t.Errorf("%q: expected match %t with serial %s", selector, matches, sim.Serial)
+		}
+	}
+}
+
+func TestOpenBySelector(t *testing.T) {
+	// The registered backends are global, restore them for the other tests
+	defer func(backends []Backend) { registeredBackends = backends }(registeredBackends)
+	var sims = []*SimBackend{NewSimBackend(), NewSimBackend()}
+	for _, sim := range sims {
+		RegisterBackend(sim)
+	}
+
+	if _, err := OpenBySelector("media=SIM, serial=FFFFFFFFFFFFFFFF"); !errors.Is(err, ErrDeviceNotFound) {
+		t.Errorf("expected ErrDeviceNotFound when no device matches, got %v", err)
+	}
+	if _, err := OpenBySelector("LimeSDR Simulator, media=SIM"); !errors.Is(err, ErrMultipleDevices) {
+		t.Errorf("expected ErrMultipleDevices when both simulated devices match, got %v", err)
+	}
+	if _, err := OpenBySelector("LimeSDR-USB, LimeSDR Mini"); err == nil || errors.Is(err, ErrDeviceNotFound) {
+		t.Errorf("expected a selector error, got %v", err)
+	}
+
+	list, err := GetDevicesFrom(sims[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	d, err := OpenBySelector("media=SIM, serial=" + list[0].Serial)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer d.Close()
+	if d.DeviceInfo.Serial != list[0].Serial {
+		t.Errorf("expected the device with serial %s, got %s", list[0].Serial, d.DeviceInfo.Serial)
+	}
+}
@@ -0,0 +1,1371 @@
+package limedrv
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// SimSignalType is the kind of signal synthesized by a SimBackend
+type SimSignalType int
+
+const (
+	// SimTone is a single carrier at Frequency
+	SimTone SimSignalType = iota
+	// SimNoise is a white gaussian noise over all the band. Frequency is ignored.
+	SimNoise
+	// SimFM is a carrier at Frequency modulated in frequency by a tone of ModulationFrequency with Deviation
+	SimFM
+)
+
+// SimSignal is a signal present in the "air" of a SimBackend.
+// Every RX channel receives all signals that are inside its bandwidth (defined by sample rate, LPF and GFIR).
+type SimSignal struct {
+	Type SimSignalType
+	// Frequency of the carrier in Hertz
+	Frequency float64
+	// Amplitude of the signal when the channel is at maximum gain. 1 means full scale.
+	Amplitude float64
+	// Deviation of the FM carrier in Hertz (SimFM only)
+	Deviation float64
+	// ModulationFrequency of the FM modulating tone in Hertz (SimFM only)
+	ModulationFrequency float64
+}
+
+// Simulated LMS7 Board characteristics
+const (
+	simChannels      = 2
+	simMinSampleRate = 100e3
+	simMaxSampleRate = 61.44e6
+	simMinLO         = 30e6
+	simMaxLO         = 3800e6
+	simRXMaxGain     = 73
+	simTXMaxGain     = 52
+	simTemperature   = 42.5
+)
+
+var (
+	simRXLPFRange = Range{Min: 1.4001e6, Max: 130e6}
+	simTXLPFRange = Range{Min: 5e6, Max: 130e6}
+
+	simRXAntennas = []string{NONE, LNAH, LNAL, LNAW, LB1, LB2}
+	simTXAntennas = []string{NONE, BAND1, BAND2}
+
+	simAntennaRanges = map[string]Range{
+		NONE:  {Min: 0, Max: 0, Step: 1},
+		LNAH:  {Min: 2e9, Max: 2.6e9, Step: 1},
+		LNAL:  {Min: 700e6, Max: 900e6, Step: 1},
+		LNAW:  {Min: 700e6, Max: 2.6e9, Step: 1},
+		LB1:   {Min: simMinLO, Max: simMaxLO, Step: 1},
+		LB2:   {Min: simMinLO, Max: simMaxLO, Step: 1},
+		BAND1: {Min: 2e9, Max: 2.6e9, Step: 1},
+		BAND2: {Min: 30e6, Max: 1.9e9, Step: 1},
+	}
+)
+
+// simGPIOBanks is the number of GPIO banks of 8 pins in the simulated device
+const simGPIOBanks = 1
+
+// simVCTCXODefault is the trim value of the simulated reference oscillator DAC after a reset
+const simVCTCXODefault = 128
+
+// simReferenceClock is the reference clock of the simulated LMS chip, the same of a LimeSDR
+const simReferenceClock = 30.72e6
+
+// simLibraryVersion is the library version reported by the simulator
+const simLibraryVersion = "simulator"
+
+// simProgramModes are the program modes of the simulated device, the same of a LimeSDR USB
+var simProgramModes = []string{"Automatic", "FPGA FLASH", "FPGA Reset", "FX3 FLASH", "FX3 Reset"}
+
+// simProgramChunk is the number of bytes written between progress reports when programming the simulated device
+const simProgramChunk = 65536
+
+// simChipVersion is the value of the VER, REV and MASK register (0x002F) of the simulated LMS7002M
+const simChipVersion = 0x3841
+
+type simChannel struct {
+	enabled      bool
+	antenna      int
+	frequency    float64
+	gain         uint
+	lpfBandwidth float64
+	lpfEnabled   bool
+	gfirLPF      float64
+	gfirEnabled  bool
+	// calibrations counts the calibrations that ran, not counting the ones loaded from the calibration cache
+	calibrations int
+	// calibrationCache has the LO frequency and bandwidth of each calibration that ran
+	calibrationCache map[[2]float64]bool
+
+	ncoFrequencies [NCOValueCount]float64
+	ncoPhases      [NCOValueCount]float64
+	ncoPhaseOffset float64
+	ncoFrequency   float64
+	ncoPhaseMode   bool
+	ncoIndex       int
+	ncoDownConvert bool
+
+	testSignal TestSignal
+	testDCI    int16
+	testDCQ    int16
+}
+
+// tunedFrequency returns the frequency received at baseband, which is the LO shifted by the NCO
+func (c *simChannel) tunedFrequency() float64 {
+	if c.ncoIndex < 0 {
+		return c.frequency
+	}
+
+	var shift = c.ncoFrequencies[c.ncoIndex]
+	if c.ncoPhaseMode {
+		shift = c.ncoFrequency
+	}
+
+	if c.ncoDownConvert {
+		return c.frequency + shift
+	}
+	return c.frequency - shift
+}
+
+type simDevice struct {
+	hostSampleRate float64
+	rfSampleRate   float64
+	rx             [simChannels]simChannel
+	tx             [simChannels]simChannel
+	calibCache     bool
+	gpioLevels     [simGPIOBanks]byte
+	gpioOutputs    [simGPIOBanks]byte
+	vctcxo         uint16
+	referenceClock float64
+	externalRef    float64
+	lmsRegisters   map[uint16]uint16
+	fpgaRegisters  map[uint16]uint16
+	waveform       [][]complex64
+	wfmPlaying     [simChannels]bool
+}
+
+func (d *simDevice) reset() {
+	d.hostSampleRate = 1e6
+	d.rfSampleRate = 4e6
+	d.vctcxo = simVCTCXODefault
+	d.referenceClock = simReferenceClock
+	d.externalRef = 0
+	d.lmsRegisters = map[uint16]uint16{0x002F: simChipVersion}
+	d.fpgaRegisters = make(map[uint16]uint16)
+	d.waveform = nil
+	d.wfmPlaying = [simChannels]bool{}
+	for i := 0; i < simChannels; i++ {
+		d.rx[i] = simChannel{antenna: 3, frequency: 100e6, lpfBandwidth: simRXLPFRange.Max, ncoIndex: -1}
+		d.tx[i] = simChannel{antenna: 1, frequency: 100e6, lpfBandwidth: simTXLPFRange.Max, ncoIndex: -1}
+	}
+}
+
+func (d *simDevice) channel(isRX bool, channel int) (*simChannel, error) {
+	if channel < 0 || channel >= simChannels {
+		return nil, fmt.Errorf("channel %d: %w", channel, ErrInvalidChannel)
+	}
+	if isRX {
+		return &d.rx[channel], nil
+	}
+	return &d.tx[channel], nil
+}
+
+type simStream struct {
+	dev       *simDevice
+	config    StreamConfig
+	running   bool
+	timestamp uint64
+	next      time.Time
+	underruns int
+	overruns  int
+	phases    []float64
+	modPhases []float64
+	testPhase float64
+	random    *rand.Rand
+}
+
+// SimBackend is a Backend that simulates a LMS7 board with two RX and two TX channels.
+// It synthesizes IQ samples from a list of SimSignal, following the channel center frequency, gain,
+// filters and sample rate. TX samples are consumed at the configured sample rate and discarded.
+// Use RegisterBackend to make its device show up in GetDevices.
+type SimBackend struct {
+	mtx        sync.Mutex
+	signals    []SimSignal
+	seed       int64
+	devices    map[uintptr]*simDevice
+	streams    map[uintptr]*simStream
+	lastHandle uintptr
+	id         int
+	unplugged  bool
+}
+
+var simBackendCount int32
+
+// NewSimBackend creates a simulated backend with a single device that receives the specified signals
+func NewSimBackend(signals ...SimSignal) *SimBackend {
+	return &SimBackend{
+		signals: signals,
+		seed:    time.Now().UnixNano(),
+		devices: make(map[uintptr]*simDevice),
+		streams: make(map[uintptr]*simStream),
+		id:      int(atomic.AddInt32(&simBackendCount, 1)),
+	}
+}
+
+// SetSignals replaces the signals received by the simulated device. Running streams are updated on the fly.
+func (s *SimBackend) SetSignals(signals ...SimSignal) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	s.signals = signals
+	for _, st := range s.streams {
+		st.phases = nil
+		st.modPhases = nil
+	}
+}
+
+// Signals returns the signals received by the simulated device
+func (s *SimBackend) Signals() []SimSignal {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	return append([]SimSignal{}, s.signals...)
+}
+
+// SetSeed sets the seed used to generate noise in new streams. Useful for reproducible tests.
+func (s *SimBackend) SetSeed(seed int64) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	s.seed = seed
+}
+
+func (s *SimBackend) device(dev uintptr) (*simDevice, error) {
+	d, ok := s.devices[dev]
+	if !ok {
+		return nil, fmt.Errorf("simulated device %d not open: %w", dev, ErrDeviceNotFound)
+	}
+	return d, nil
+}
+
+func (s *SimBackend) channel(dev uintptr, isRX bool, channel int) (*simChannel, error) {
+	d, err := s.device(dev)
+	if err != nil {
+		return nil, err
+	}
+	return d.channel(isRX, channel)
+}
+
+func (s *SimBackend) stream(stream uintptr) (*simStream, error) {
+	st, ok := s.streams[stream]
+	if !ok {
+		return nil, fmt.Errorf("stream %d not found: %w", stream, ErrStreamFailure)
+	}
+	return st, nil
+}
+
+func checkRange(name string, value float64, r Range) error {
+	if value < r.Min || value > r.Max {
+		return fmt.Errorf("%s %.0f is not in [%.0f, %.0f]: %w", name, value, r.Min, r.Max, ErrOutOfRange)
+	}
+	return nil
+}
+
+// region Backend
+
+// SetPlugged simulates plugging in or removing the board of the simulated device.
+// While it is removed, the device is not listed and its opened handles are reported as disconnected.
+func (s *SimBackend) SetPlugged(plugged bool) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	s.unplugged = !plugged
+}
+
+// DropSamples simulates a overrun that loses count samples in the running RX streams of the simulated device.
+// Like in the hardware, the sample counter of the streams skips the lost samples.
+func (s *SimBackend) DropSamples(count int) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	for _, st := range s.streams {
+		if st.running && st.config.IsRX {
+			st.timestamp += uint64(count)
+			st.overruns++
+		}
+	}
+}
+
+// GetDeviceList returns the simulated device
+func (s *SimBackend) GetDeviceList() ([]DeviceInfo, error) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	if s.unplugged {
+		return []DeviceInfo{}, nil
+	}
+	return []DeviceInfo{
+		{
+			DeviceName:        "LimeSDR Simulator",
+			Media:             "SIM",
+			Module:            "sim",
+			Addr:              fmt.Sprintf("sim:%d", s.id),
+			Serial:            fmt.Sprintf("SIM%013X", s.id),
+			BoardSerialNumber: uint64(s.id),
+		},
+	}, nil
+}
+
+// Open opens the simulated device
+func (s *SimBackend) Open(device DeviceInfo) (uintptr, error) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	if s.unplugged {
+		return 0, fmt.Errorf("simulated device is unplugged: %w", ErrDeviceNotFound)
+	}
+	s.lastHandle++
+	d := &simDevice{}
+	d.reset()
+	s.devices[s.lastHandle] = d
+	return s.lastHandle, nil
+}
+
+// Close closes the simulated device and destroy its streams
+func (s *SimBackend) Close(dev uintptr) error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	d, err := s.device(dev)
+	if err != nil {
+		return err
+	}
+	for h, st := range s.streams {
+		if st.dev == d {
+			delete(s.streams, h)
+		}
+	}
+	delete(s.devices, dev)
+	return nil
+}
+
+// IsOpen returns true if the simulated device is open and its board is plugged in
+func (s *SimBackend) IsOpen(dev uintptr) bool {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	_, err := s.device(dev)
+	return err == nil && !s.unplugged
+}
+
+// GetDeviceInfo returns the information of the simulated device, that has no expansion board
+func (s *SimBackend) GetDeviceInfo(dev uintptr) (BoardInfo, error) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	if _, err := s.device(dev); err != nil {
+		return BoardInfo{}, err
+	}
+	return BoardInfo{
+		DeviceName:          "LimeSDR Simulator",
+		ExpansionName:       "UNSUPPORTED",
+		FirmwareVersion:     "0",
+		HardwareVersion:     "0",
+		ProtocolVersion:     "0",
+		BoardSerialNumber:   uint64(s.id),
+		GatewareVersion:     "0",
+		GatewareTargetBoard: "LimeSDR-SIM",
+	}, nil
+}
+
+// GetLibraryVersion returns the version of the simulator
+func (s *SimBackend) GetLibraryVersion() string {
+	return simLibraryVersion
+}
+
+// SetLogHandler does nothing, since the simulator does not write log messages
+func (s *SimBackend) SetLogHandler(handler func(level LogLevel, message string)) {}
+
+// Reset restores the simulated device default configuration
+func (s *SimBackend) Reset(dev uintptr) error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	d, err := s.device(dev)
+	if err != nil {
+		return err
+	}
+	d.reset()
+	return nil
+}
+
+// Init restores the simulated device default configuration
+func (s *SimBackend) Init(dev uintptr) error {
+	return s.Reset(dev)
+}
+
+// GetNumChannels returns the number of channels of the simulated device
+func (s *SimBackend) GetNumChannels(dev uintptr, isRX bool) (int, error) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	if _, err := s.device(dev); err != nil {
+		return 0, err
+	}
+	return simChannels, nil
+}
+
+// EnableChannel enables or disables a simulated channel
+func (s *SimBackend) EnableChannel(dev uintptr, isRX bool, channel int, enabled bool) error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	ch, err := s.channel(dev, isRX, channel)
+	if err != nil {
+		return err
+	}
+	ch.enabled = enabled
+	return nil
+}
+
+// GetSampleRateRange returns the sample rate range of the simulated device
+func (s *SimBackend) GetSampleRateRange(dev uintptr, isRX bool) (Range, error) {
+	return Range{Min: simMinSampleRate, Max: simMaxSampleRate}, nil
+}
+
+// SetSampleRate sets the simulated device sample rate
+func (s *SimBackend) SetSampleRate(dev uintptr, sampleRate float64, oversample int) error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	d, err := s.device(dev)
+	if err != nil {
+		return err
+	}
+	if err := checkRange("sample rate", sampleRate, Range{Min: simMinSampleRate, Max: simMaxSampleRate}); err != nil {
+		return err
+	}
+	if oversample < 1 {
+		oversample = 1
+	}
+	d.hostSampleRate = sampleRate
+	d.rfSampleRate = sampleRate * float64(oversample)
+	return nil
+}
+
+// GetSampleRate returns the simulated device host and rf sample rate
+func (s *SimBackend) GetSampleRate(dev uintptr, isRX bool, channel int) (float64, float64, error) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	d, err := s.device(dev)
+	if err != nil {
+		return 0, 0, err
+	}
+	return d.hostSampleRate, d.rfSampleRate, nil
+}
+
+// GetChipTemperature returns a fixed temperature
+func (s *SimBackend) GetChipTemperature(dev uintptr, index int) (float64, error) {
+	return simTemperature, nil
+}
+
+func simMaxGain(isRX bool) uint {
+	if isRX {
+		return simRXMaxGain
+	}
+	return simTXMaxGain
+}
+
+// SetGaindB sets the simulated channel gain
+func (s *SimBackend) SetGaindB(dev uintptr, isRX bool, channel int, gain uint) error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	ch, err := s.channel(dev, isRX, channel)
+	if err != nil {
+		return err
+	}
+	if err := checkRange("gain", float64(gain), Range{Max: float64(simMaxGain(isRX))}); err != nil {
+		return err
+	}
+	ch.gain = gain
+	return nil
+}
+
+// GetGaindB returns the simulated channel gain
+func (s *SimBackend) GetGaindB(dev uintptr, isRX bool, channel int) (uint, error) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	ch, err := s.channel(dev, isRX, channel)
+	if err != nil {
+		return 0, err
+	}
+	return ch.gain, nil
+}
+
+// SetNormalizedGain sets the simulated channel gain
+func (s *SimBackend) SetNormalizedGain(dev uintptr, isRX bool, channel int, gain float64) error {
+	if err := checkRange("normalized gain", gain, Range{Max: 1}); err != nil {
+		return err
+	}
+	return s.SetGaindB(dev, isRX, channel, uint(math.Round(gain*float64(simMaxGain(isRX)))))
+}
+
+// GetNormalizedGain returns the simulated channel gain
+func (s *SimBackend) GetNormalizedGain(dev uintptr, isRX bool, channel int) (float64, error) {
+	gain, err := s.GetGaindB(dev, isRX, channel)
+	if err != nil {
+		return 0, err
+	}
+	return float64(gain) / float64(simMaxGain(isRX)), nil
+}
+
+// GetLPFBWRange returns the simulated analog low pass filter range
+func (s *SimBackend) GetLPFBWRange(dev uintptr, isRX bool) (Range, error) {
+	if isRX {
+		return simRXLPFRange, nil
+	}
+	return simTXLPFRange, nil
+}
+
+// SetLPFBW sets the simulated analog low pass filter bandwidth
+func (s *SimBackend) SetLPFBW(dev uintptr, isRX bool, channel int, bandwidth float64) error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	ch, err := s.channel(dev, isRX, channel)
+	if err != nil {
+		return err
+	}
+	var r = simTXLPFRange
+	if isRX {
+		r = simRXLPFRange
+	}
+	if err := checkRange("LPF bandwidth", bandwidth, r); err != nil {
+		return err
+	}
+	ch.lpfBandwidth = bandwidth
+	return nil
+}
+
+// GetLPFBW returns the simulated analog low pass filter bandwidth
+func (s *SimBackend) GetLPFBW(dev uintptr, isRX bool, channel int) (float64, error) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	ch, err := s.channel(dev, isRX, channel)
+	if err != nil {
+		return 0, err
+	}
+	return ch.lpfBandwidth, nil
+}
+
+// SetLPF enables or disables the simulated analog low pass filter
+func (s *SimBackend) SetLPF(dev uintptr, isRX bool, channel int, enabled bool) error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	ch, err := s.channel(dev, isRX, channel)
+	if err != nil {
+		return err
+	}
+	ch.lpfEnabled = enabled
+	return nil
+}
+
+// SetGFIRLPF configures the simulated digital low pass filter
+func (s *SimBackend) SetGFIRLPF(dev uintptr, isRX bool, channel int, enabled bool, bandwidth float64) error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	ch, err := s.channel(dev, isRX, channel)
+	if err != nil {
+		return err
+	}
+	ch.gfirEnabled = enabled
+	ch.gfirLPF = bandwidth
+	return nil
+}
+
+// SetGFIRCoeff accepts the taps but they're not applied to the simulated signal
+func (s *SimBackend) SetGFIRCoeff(dev uintptr, isRX bool, channel int, gfir int, taps []float64) error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	_, err := s.channel(dev, isRX, channel)
+	return err
+}
+
+// SetGFIR enables or disables manual GFIR taps. They're not applied to the simulated signal.
+func (s *SimBackend) SetGFIR(dev uintptr, isRX bool, channel int, gfir int, enabled bool) error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	ch, err := s.channel(dev, isRX, channel)
+	if err != nil {
+		return err
+	}
+	ch.gfirEnabled = false
+	return nil
+}
+
+// SetNCOFrequency loads the simulated NCO frequency table. The NCO shifts the frequency received by the channel.
+func (s *SimBackend) SetNCOFrequency(dev uintptr, isRX bool, channel int, frequencies []float64, phaseOffset float64) error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	ch, err := s.channel(dev, isRX, channel)
+	if err != nil {
+		return err
+	}
+	copy(ch.ncoFrequencies[:], frequencies)
+	ch.ncoPhaseOffset = phaseOffset
+	ch.ncoPhaseMode = false
+	return nil
+}
+
+// GetNCOFrequency returns the simulated NCO frequency table
+func (s *SimBackend) GetNCOFrequency(dev uintptr, isRX bool, channel int) ([]float64, float64, error) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	ch, err := s.channel(dev, isRX, channel)
+	if err != nil {
+		return nil, 0, err
+	}
+	return append([]float64{}, ch.ncoFrequencies[:]...), ch.ncoPhaseOffset, nil
+}
+
+// SetNCOPhase loads the simulated NCO phase table
+func (s *SimBackend) SetNCOPhase(dev uintptr, isRX bool, channel int, phases []float64, frequency float64) error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	ch, err := s.channel(dev, isRX, channel)
+	if err != nil {
+		return err
+	}
+	copy(ch.ncoPhases[:], phases)
+	ch.ncoFrequency = frequency
+	ch.ncoPhaseMode = true
+	return nil
+}
+
+// GetNCOPhase returns the simulated NCO phase table
+func (s *SimBackend) GetNCOPhase(dev uintptr, isRX bool, channel int) ([]float64, float64, error) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	ch, err := s.channel(dev, isRX, channel)
+	if err != nil {
+		return nil, 0, err
+	}
+	return append([]float64{}, ch.ncoPhases[:]...), ch.ncoFrequency, nil
+}
+
+// SetNCOIndex selects the simulated NCO table entry, or disables the NCO if index is -1
+func (s *SimBackend) SetNCOIndex(dev uintptr, isRX bool, channel int, index int, downConvert bool) error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	ch, err := s.channel(dev, isRX, channel)
+	if err != nil {
+		return err
+	}
+	if index < -1 || index >= NCOValueCount {
+		return fmt.Errorf("NCO index %d: %w", index, ErrOutOfRange)
+	}
+	ch.ncoIndex = index
+	ch.ncoDownConvert = downConvert
+	return nil
+}
+
+// GetNCOIndex returns the simulated NCO table entry. Like LimeSuite, it fails if the NCO is disabled.
+func (s *SimBackend) GetNCOIndex(dev uintptr, isRX bool, channel int) (int, error) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	ch, err := s.channel(dev, isRX, channel)
+	if err != nil {
+		return 0, err
+	}
+	if ch.ncoIndex < 0 {
+		return 0, errors.New("NCO is disabled")
+	}
+	return ch.ncoIndex, nil
+}
+
+// SetTestSignal replaces the synthesized signals of the simulated channel by a test signal.
+// The test tones are at the host sample rate divided by 8 or 4.
+func (s *SimBackend) SetTestSignal(dev uintptr, isRX bool, channel int, signal TestSignal, dcI, dcQ int16) error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	ch, err := s.channel(dev, isRX, channel)
+	if err != nil {
+		return err
+	}
+	ch.testSignal = signal
+	ch.testDCI = dcI
+	ch.testDCQ = dcQ
+	return nil
+}
+
+// GetTestSignal returns the test signal of the simulated channel
+func (s *SimBackend) GetTestSignal(dev uintptr, isRX bool, channel int) (TestSignal, error) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	ch, err := s.channel(dev, isRX, channel)
+	if err != nil {
+		return TestSignalNone, err
+	}
+	return ch.testSignal, nil
+}
+
+// gpioBanks checks that count banks exist in the simulated device
+func (s *SimBackend) gpioBanks(dev uintptr, count int) (*simDevice, error) {
+	d, err := s.device(dev)
+	if err != nil {
+		return nil, err
+	}
+	if count <= 0 || count > simGPIOBanks {
+		return nil, fmt.Errorf("%d GPIO banks requested, device has %d: %w", count, simGPIOBanks, ErrOutOfRange)
+	}
+	return d, nil
+}
+
+// GPIORead returns the levels of the simulated GPIO. Input pins are always read as low.
+func (s *SimBackend) GPIORead(dev uintptr, count int) ([]byte, error) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	d, err := s.gpioBanks(dev, count)
+	if err != nil {
+		return nil, err
+	}
+	var banks = make([]byte, count)
+	for i := range banks {
+		banks[i] = d.gpioLevels[i] & d.gpioOutputs[i]
+	}
+	return banks, nil
+}
+
+// GPIOWrite sets the levels of the simulated GPIO
+func (s *SimBackend) GPIOWrite(dev uintptr, banks []byte) error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	d, err := s.gpioBanks(dev, len(banks))
+	if err != nil {
+		return err
+	}
+	copy(d.gpioLevels[:], banks)
+	return nil
+}
+
+// GPIODirRead returns the directions of the simulated GPIO
+func (s *SimBackend) GPIODirRead(dev uintptr, count int) ([]byte, error) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	d, err := s.gpioBanks(dev, count)
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte{}, d.gpioOutputs[:count]...), nil
+}
+
+// GPIODirWrite sets the directions of the simulated GPIO
+func (s *SimBackend) GPIODirWrite(dev uintptr, banks []byte) error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	d, err := s.gpioBanks(dev, len(banks))
+	if err != nil {
+		return err
+	}
+	copy(d.gpioOutputs[:], banks)
+	return nil
+}
+
+// Calibrate counts a calibration of the simulated channel. The simulator has no DC offset or IQ imbalance to correct.
+// Like in LimeSuite, while the calibration cache is enabled a calibration at the same frequency and bandwidth of a previous one
+// is loaded from the cache instead of running again.
+func (s *SimBackend) Calibrate(dev uintptr, isRX bool, channel int, bandwidth float64) error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	d, err := s.device(dev)
+	if err != nil {
+		return err
+	}
+	ch, err := d.channel(isRX, channel)
+	if err != nil {
+		return err
+	}
+	if bandwidth <= 0 {
+		return fmt.Errorf("calibration bandwidth %.0f: %w", bandwidth, ErrOutOfRange)
+	}
+
+	var key = [2]float64{ch.frequency, bandwidth}
+	if d.calibCache && ch.calibrationCache[key] {
+		return nil
+	}
+	if ch.calibrationCache == nil {
+		ch.calibrationCache = make(map[[2]float64]bool)
+	}
+	ch.calibrationCache[key] = true
+	ch.calibrations++
+	return nil
+}
+
+// EnableCalibCache enables or disables the simulated calibration cache
+func (s *SimBackend) EnableCalibCache(dev uintptr, enabled bool) error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	d, err := s.device(dev)
+	if err != nil {
+		return err
+	}
+	d.calibCache = enabled
+	return nil
+}
+
+// VCTCXORead returns the simulated reference oscillator trim value
+func (s *SimBackend) VCTCXORead(dev uintptr) (uint16, error) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	d, err := s.device(dev)
+	if err != nil {
+		return 0, err
+	}
+	return d.vctcxo, nil
+}
+
+// VCTCXOWrite sets the simulated reference oscillator trim value. It does not affect the simulated frequencies.
+func (s *SimBackend) VCTCXOWrite(dev uintptr, value uint16) error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	d, err := s.device(dev)
+	if err != nil {
+		return err
+	}
+	d.vctcxo = value
+	return nil
+}
+
+// GetClockFreq returns the frequency of a simulated clock. The CGEN runs at 4 times the RF sample rate, like in a LimeSDR.
+// While an external reference is set, it is the reference clock.
+func (s *SimBackend) GetClockFreq(dev uintptr, clock Clock) (float64, error) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	d, err := s.device(dev)
+	if err != nil {
+		return 0, err
+	}
+	switch clock {
+	case ClockReference:
+		if d.externalRef > 0 {
+			return d.externalRef, nil
+		}
+		return d.referenceClock, nil
+	case ClockSXR:
+		return d.rx[0].frequency, nil
+	case ClockSXT:
+		return d.tx[0].frequency, nil
+	case ClockCGEN:
+		return d.rfSampleRate * 4, nil
+	case ClockRXTSP, ClockTXTSP:
+		return d.rfSampleRate, nil
+	}
+	return 0, fmt.Errorf("clock %s cannot be read: %w", clock, ErrOutOfRange)
+}
+
+// SetClockFreq sets the frequency of a simulated clock. Setting the CGEN keeps the oversample of the sample rate.
+func (s *SimBackend) SetClockFreq(dev uintptr, clock Clock, frequency float64) error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	d, err := s.device(dev)
+	if err != nil {
+		return err
+	}
+	switch clock {
+	case ClockReference:
+		if frequency <= 0 {
+			return fmt.Errorf("reference clock %.0f: %w", frequency, ErrOutOfRange)
+		}
+		d.referenceClock = frequency
+	case ClockSXR, ClockSXT:
+		if err := checkRange("LO frequency", frequency, Range{Min: simMinLO, Max: simMaxLO}); err != nil {
+			return err
+		}
+		for i := 0; i < simChannels; i++ {
+			ch, _ := d.channel(clock == ClockSXR, i)
+			ch.frequency = frequency
+		}
+	case ClockCGEN:
+		var oversample = d.rfSampleRate / d.hostSampleRate
+		if err := checkRange("sample rate", frequency/4/oversample, Range{Min: simMinSampleRate, Max: simMaxSampleRate}); err != nil {
+			return err
+		}
+		d.rfSampleRate = frequency / 4
+		d.hostSampleRate = d.rfSampleRate / oversample
+	case ClockExternalReference:
+		d.externalRef = frequency
+	default:
+		return fmt.Errorf("clock %s cannot be set: %w", clock, ErrOutOfRange)
+	}
+	return nil
+}
+
+// ReadLMSReg returns the value of a simulated LMS7 register. Registers never written read as 0.
+func (s *SimBackend) ReadLMSReg(dev uintptr, address uint16) (uint16, error) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	d, err := s.device(dev)
+	if err != nil {
+		return 0, err
+	}
+	return d.lmsRegisters[address], nil
+}
+
+// WriteLMSReg sets the value of a simulated LMS7 register. The registers do not affect the simulation.
+func (s *SimBackend) WriteLMSReg(dev uintptr, address uint16, value uint16) error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	d, err := s.device(dev)
+	if err != nil {
+		return err
+	}
+	d.lmsRegisters[address] = value
+	return nil
+}
+
+// ReadFPGAReg returns the value of a simulated FPGA register. Registers never written read as 0.
+func (s *SimBackend) ReadFPGAReg(dev uintptr, address uint16) (uint16, error) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	d, err := s.device(dev)
+	if err != nil {
+		return 0, err
+	}
+	return d.fpgaRegisters[address], nil
+}
+
+// WriteFPGAReg sets the value of a simulated FPGA register. The registers do not affect the simulation.
+func (s *SimBackend) WriteFPGAReg(dev uintptr, address uint16, value uint16) error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	d, err := s.device(dev)
+	if err != nil {
+		return err
+	}
+	d.fpgaRegisters[address] = value
+	return nil
+}
+
+// Synchronize does nothing, since the simulated registers have no cache
+func (s *SimBackend) Synchronize(dev uintptr, toChip bool) error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	_, err := s.device(dev)
+	return err
+}
+
+// GetProgramModes returns the program modes of the simulated device
+func (s *SimBackend) GetProgramModes(dev uintptr) ([]string, error) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	if _, err := s.device(dev); err != nil {
+		return nil, err
+	}
+	return append([]string{}, simProgramModes...), nil
+}
+
+// Program pretends to write the image to the simulated device, reporting the progress in chunks
+func (s *SimBackend) Program(dev uintptr, mode string, image []byte, progress func(done, total int) bool) error {
+	s.mtx.Lock()
+	_, err := s.device(dev)
+	s.mtx.Unlock()
+	if err != nil {
+		return err
+	}
+
+	var found = false
+	for _, m := range simProgramModes {
+		found = found || m == mode
+	}
+	if !found {
+		return fmt.Errorf("program mode %q: %w", mode, ErrOutOfRange)
+	}
+
+	for done := 0; ; done += simProgramChunk {
+		if done > len(image) {
+			done = len(image)
+		}
+		if progress != nil && !progress(done, len(image)) {
+			return ErrAborted
+		}
+		if done == len(image) {
+			return nil
+		}
+	}
+}
+
+// UploadWFM stores the waveform in the simulated FPGA memory
+func (s *SimBackend) UploadWFM(dev uintptr, buffers [][]byte, sampleCount int, format int) error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	d, err := s.device(dev)
+	if err != nil {
+		return err
+	}
+	if len(buffers) == 0 || len(buffers) > simChannels {
+		return fmt.Errorf("waveform with %d channels: %w", len(buffers), ErrInvalidChannel)
+	}
+
+	var scale = float32(1 / IQFormatFullScale(format))
+	d.waveform = make([][]complex64, len(buffers))
+	for i, buffer := range buffers {
+		if len(buffer) < sampleCount*2*iqFormatSampleSize(format) {
+			return fmt.Errorf("waveform buffer %d has %d bytes: %w", i, len(buffer), ErrOutOfRange)
+		}
+		d.waveform[i] = make([]complex64, sampleCount)
+		decodeIQ(d.waveform[i], buffer, format, scale)
+	}
+	return nil
+}
+
+// EnableTxWFM starts or stops the simulated waveform playback. While it plays, SendStream fails for the channel.
+func (s *SimBackend) EnableTxWFM(dev uintptr, channel int, enabled bool) error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	d, err := s.device(dev)
+	if err != nil {
+		return err
+	}
+	if channel < 0 || channel >= simChannels {
+		return fmt.Errorf("channel %d: %w", channel, ErrInvalidChannel)
+	}
+	if enabled && channel >= len(d.waveform) {
+		return fmt.Errorf("no waveform uploaded for channel %d: %w", channel, ErrStreamFailure)
+	}
+	d.wfmPlaying[channel] = enabled
+	return nil
+}
+
+// SetLOFrequency sets the simulated LO frequency of all channels in the direction
+func (s *SimBackend) SetLOFrequency(dev uintptr, isRX bool, channel int, frequency float64) error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	d, err := s.device(dev)
+	if err != nil {
+		return err
+	}
+	if _, err := d.channel(isRX, channel); err != nil {
+		return err
+	}
+	if err := checkRange("LO frequency", frequency, Range{Min: simMinLO, Max: simMaxLO}); err != nil {
+		return err
+	}
+	// Like the LMS7, all channels of the same direction share the LO
+	for i := 0; i < simChannels; i++ {
+		ch, _ := d.channel(isRX, i)
+		ch.frequency = frequency
+	}
+	return nil
+}
+
+// GetLOFrequency returns the simulated channel center frequency
+func (s *SimBackend) GetLOFrequency(dev uintptr, isRX bool, channel int) (float64, error) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	ch, err := s.channel(dev, isRX, channel)
+	if err != nil {
+		return 0, err
+	}
+	return ch.frequency, nil
+}
+
+// GetAntennaList returns the same antenna list of a LMS7 board
+func (s *SimBackend) GetAntennaList(dev uintptr, isRX bool, channel int) ([]string, error) {
+	if isRX {
+		return append([]string{}, simRXAntennas...), nil
+	}
+	return append([]string{}, simTXAntennas...), nil
+}
+
+// GetAntennaBW returns the frequency range of the simulated antenna port
+func (s *SimBackend) GetAntennaBW(dev uintptr, isRX bool, channel int, antenna int) (Range, error) {
+	var list = simTXAntennas
+	if isRX {
+		list = simRXAntennas
+	}
+	if antenna < 0 || antenna >= len(list) {
+		return Range{}, fmt.Errorf("antenna %d: %w", antenna, ErrAntennaNotFound)
+	}
+	return simAntennaRanges[list[antenna]], nil
+}
+
+// SetAntenna selects the simulated antenna port. Selecting NONE mutes the channel.
+func (s *SimBackend) SetAntenna(dev uintptr, isRX bool, channel int, antenna int) error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	ch, err := s.channel(dev, isRX, channel)
+	if err != nil {
+		return err
+	}
+	var list = simTXAntennas
+	if isRX {
+		list = simRXAntennas
+	}
+	if antenna < 0 || antenna >= len(list) {
+		return fmt.Errorf("antenna %d: %w", antenna, ErrAntennaNotFound)
+	}
+	ch.antenna = antenna
+	return nil
+}
+
+// SetupStream creates a simulated stream
+func (s *SimBackend) SetupStream(dev uintptr, config StreamConfig) (uintptr, error) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	d, err := s.device(dev)
+	if err != nil {
+		return 0, err
+	}
+	if _, err := d.channel(config.IsRX, config.Channel); err != nil {
+		return 0, err
+	}
+	s.lastHandle++
+	s.streams[s.lastHandle] = &simStream{
+		dev:    d,
+		config: config,
+		random: rand.New(rand.NewSource(s.seed + int64(s.lastHandle))),
+	}
+	return s.lastHandle, nil
+}
+
+// DestroyStream destroys a simulated stream
+func (s *SimBackend) DestroyStream(dev uintptr, stream uintptr) error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	if _, err := s.stream(stream); err != nil {
+		return err
+	}
+	delete(s.streams, stream)
+	return nil
+}
+
+// StartStream starts a simulated stream
+func (s *SimBackend) StartStream(stream uintptr) error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	st, err := s.stream(stream)
+	if err != nil {
+		return err
+	}
+	st.running = true
+	st.next = time.Now()
+	return nil
+}
+
+// StopStream stops a simulated stream
+func (s *SimBackend) StopStream(stream uintptr) error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	st, err := s.stream(stream)
+	if err != nil {
+		return err
+	}
+	st.running = false
+	return nil
+}
+
+// pace waits until sampleCount samples would be transferred by real hardware.
+// Returns false if that does not happen before timeout.
+func (s *SimBackend) pace(st *simStream, sampleCount int, timeoutMs uint) bool {
+	var duration = time.Duration(float64(sampleCount) / st.dev.hostSampleRate * float64(time.Second))
+	var wait = time.Until(st.next.Add(duration))
+	var timeout = time.Duration(timeoutMs) * time.Millisecond
+
+	if wait > timeout {
+		s.mtx.Unlock()
+		time.Sleep(timeout)
+		s.mtx.Lock()
+		return false
+	}
+
+	if wait > 0 {
+		s.mtx.Unlock()
+		time.Sleep(wait)
+		s.mtx.Lock()
+	}
+
+	st.next = st.next.Add(duration)
+	if time.Since(st.next) > time.Second {
+		// Host is too slow. Drop the backlog like a overrun (or underrun for TX) in the hardware would.
+		st.next = time.Now()
+		if st.config.IsRX {
+			st.overruns++
+		} else {
+			st.underruns++
+		}
+	}
+
+	return true
+}
+
+// RecvStream synthesizes sampleCount samples from the signals in the channel bandwidth
+func (s *SimBackend) RecvStream(stream uintptr, buffer []byte, sampleCount int, meta *StreamMeta, timeoutMs uint) (int, error) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	st, err := s.stream(stream)
+	if err != nil {
+		return -1, err
+	}
+	if !st.running || !st.config.IsRX {
+		return -1, fmt.Errorf("stream %d is not a running RX stream: %w", stream, ErrStreamFailure)
+	}
+
+	var sampleSize = 2 * iqFormatSampleSize(st.config.Format)
+	if sampleCount > len(buffer)/sampleSize {
+		sampleCount = len(buffer) / sampleSize
+	}
+
+	if !s.pace(st, sampleCount, timeoutMs) {
+		return 0, nil
+	}
+
+	if _, ok := s.streams[stream]; !ok {
+		// Destroyed while waiting
+		return -1, fmt.Errorf("stream %d destroyed: %w", stream, ErrStreamFailure)
+	}
+
+	meta.Timestamp = st.timestamp
+	s.synthesize(st, buffer, sampleCount)
+	st.timestamp += uint64(sampleCount)
+
+	return sampleCount, nil
+}
+
+// SendStream consumes sampleCount samples at the channel sample rate. It fails while the channel plays a waveform.
+// If meta.WaitForTimestamp is set, it waits for the stream sample counter to reach meta.Timestamp first.
+func (s *SimBackend) SendStream(stream uintptr, buffer []byte, sampleCount int, meta *StreamMeta, timeoutMs uint) (int, error) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	st, err := s.stream(stream)
+	if err != nil {
+		return -1, err
+	}
+	if !st.running || st.config.IsRX {
+		return -1, fmt.Errorf("stream %d is not a running TX stream: %w", stream, ErrStreamFailure)
+	}
+	if st.dev.wfmPlaying[st.config.Channel] {
+		// The FPGA transmits the waveform instead of the streamed samples
+		return -1, fmt.Errorf("TX channel %d is playing a waveform: %w", st.config.Channel, ErrStreamFailure)
+	}
+
+	var sampleSize = 2 * iqFormatSampleSize(st.config.Format)
+	if sampleCount > len(buffer)/sampleSize {
+		sampleCount = len(buffer) / sampleSize
+	}
+
+	if meta.WaitForTimestamp && meta.Timestamp > st.timestamp {
+		// Idle until the requested sample counter
+		if !s.pace(st, int(meta.Timestamp-st.timestamp), timeoutMs) {
+			return 0, nil
+		}
+		st.timestamp = meta.Timestamp
+	}
+
+	if !s.pace(st, sampleCount, timeoutMs) {
+		return 0, nil
+	}
+
+	st.timestamp += uint64(sampleCount)
+
+	return sampleCount, nil
+}
+
+// GetStreamStatus returns the status of a simulated stream.
+// Overruns (or underruns for TX) are reported when the host falls more than a second behind the stream.
+func (s *SimBackend) GetStreamStatus(stream uintptr) (StreamStatus, error) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	st, err := s.stream(stream)
+	if err != nil {
+		return StreamStatus{}, err
+	}
+
+	var status = StreamStatus{
+		Active:    st.running,
+		FifoSize:  st.config.FifoSize,
+		Underrun:  st.underruns,
+		Overrun:   st.overruns,
+		Timestamp: st.timestamp,
+	}
+
+	if st.running {
+		status.SampleRate = st.dev.hostSampleRate
+		status.LinkRate = st.dev.hostSampleRate * float64(2*iqFormatSampleSize(st.config.Format))
+	}
+
+	st.underruns = 0
+	st.overruns = 0
+	return status, nil
+}
+
+// endregion
+
+// synthesizeTestSignal writes sampleCount samples of the channel test signal into buffer.
+// Test signals are generated after the analog chain, so they do not depend on gain, filters or antenna.
+func (s *SimBackend) synthesizeTestSignal(st *simStream, ch *simChannel, buffer []byte, sampleCount int) {
+	var amplitude, divider float64
+	switch ch.testSignal {
+	case TestSignalNCODiv8:
+		amplitude, divider = 0.5, 8
+	case TestSignalNCODiv4:
+		amplitude, divider = 0.5, 4
+	case TestSignalNCODiv8Full:
+		amplitude, divider = 1, 8
+	case TestSignalNCODiv4Full:
+		amplitude, divider = 1, 4
+	}
+
+	for i := 0; i < sampleCount; i++ {
+		var sample = complex(float64(ch.testDCI)/32767, float64(ch.testDCQ)/32767)
+		if ch.testSignal != TestSignalDC {
+			sample = complex(amplitude*math.Cos(st.testPhase), amplitude*math.Sin(st.testPhase))
+			st.testPhase = math.Mod(st.testPhase+2*math.Pi/divider, 2*math.Pi)
+		}
+		putIQSample(buffer, i, st.config.Format, clip(real(sample)), clip(imag(sample)))
+	}
+}
+
+// synthesize writes sampleCount samples of the signals received by the stream channel into buffer
+func (s *SimBackend) synthesize(st *simStream, buffer []byte, sampleCount int) {
+	var ch = &st.dev.rx[st.config.Channel]
+	var sampleRate = st.dev.hostSampleRate
+	var gain = math.Pow(10, (float64(ch.gain)-simRXMaxGain)/20)
+
+	if ch.testSignal != TestSignalNone {
+		s.synthesizeTestSignal(st, ch, buffer, sampleCount)
+		return
+	}
+
+	if len(st.phases) != len(s.signals) {
+		st.phases = make([]float64, len(s.signals))
+		st.modPhases = make([]float64, len(s.signals))
+	}
+
+	// Maximum offset from center frequency that reaches the ADC
+	var bandwidth = sampleRate / 2
+	if ch.lpfEnabled && ch.lpfBandwidth/2 < bandwidth {
+		bandwidth = ch.lpfBandwidth / 2
+	}
+	if ch.gfirEnabled && ch.gfirLPF > 0 && ch.gfirLPF/2 < bandwidth {
+		bandwidth = ch.gfirLPF / 2
+	}
+
+	for i := 0; i < sampleCount; i++ {
+		var sample complex128
+
+		if ch.antenna != 0 {
+			for n, sig := range s.signals {
+				var amplitude = sig.Amplitude * gain
+				var offset = sig.Frequency - ch.tunedFrequency()
+
+				switch sig.Type {
+				case SimNoise:
+					sample += complex(st.random.NormFloat64()*amplitude/math.Sqrt2, st.random.NormFloat64()*amplitude/math.Sqrt2)
+				case SimTone, SimFM:
+					if math.Abs(offset) > bandwidth {
+						continue
+					}
+					var instFrequency = offset
+					if sig.Type == SimFM {
+						instFrequency += sig.Deviation * math.Sin(st.modPhases[n])
+						st.modPhases[n] = math.Mod(st.modPhases[n]+2*math.Pi*sig.ModulationFrequency/sampleRate, 2*math.Pi)
+					}
+					sample += complex(amplitude*math.Cos(st.phases[n]), amplitude*math.Sin(st.phases[n]))
+					st.phases[n] = math.Mod(st.phases[n]+2*math.Pi*instFrequency/sampleRate, 2*math.Pi)
+				}
+			}
+		}
+
+		putIQSample(buffer, i, st.config.Format, clip(real(sample)), clip(imag(sample)))
+	}
+}
@@ -0,0 +1,198 @@
+package limedrv
+
+import (
+	"math"
+	"math/cmplx"
+	"testing"
+)
+
+// openSim opens the device of a SimBackend with the signals and enables RX channel 0 at centerFrequency and maximum gain.
+// The device should be closed by the caller.
+func openSim(t *testing.T, centerFrequency float64, signals ...SimSignal) *LMSDevice {
+	t.Helper()
+	devices, err := GetDevicesFrom(NewSimBackend(signals...))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(devices) != 1 {
+		t.Fatalf("expected 1 simulated device, got %d", len(devices))
+	}
+
+	d, err := Open(devices[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := d.RXChannels[0].Enable(); err != nil {
+		d.Close()
+		t.Fatal(err)
+	}
+	if err := d.RXChannels[0].SetCenterFrequency(centerFrequency); err != nil {
+		d.Close()
+		t.Fatal(err)
+	}
+	if err := d.RXChannels[0].SetGainNormalized(1); err != nil {
+		d.Close()
+		t.Fatal(err)
+	}
+	return d
+}
+
+// captureSim runs the device until count samples of RX channel 0 are received, skipping the first block
+func captureSim(t *testing.T, d *LMSDevice, count int) []complex64 {
+	t.Helper()
+	var samples = d.RXChannels[0].Samples()
+	if err := d.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer d.Stop()
+
+	<-samples
+	var ret []complex64
+	for len(ret) < count {
+		block, ok := <-samples
+		if !ok {
+			t.Fatal("samples channel closed while running")
+		}
+		ret = append(ret, block.Data...)
+	}
+	return ret[:count]
+}
+
+// peakBin returns the DFT bin with most power and its magnitude, normalized so a full scale tone is 1
+func peakBin(samples []complex64) (int, float64) {
+	var n = len(samples)
+	var best, bestMagnitude = 0, 0.0
+	for k := 0; k < n; k++ {
+		var sum complex128
+		for i, s := range samples {
+			sum += complex128(s) * cmplx.Exp(complex(0, -2*math.Pi*float64(k*i)/float64(n)))
+		}
+		if m := cmplx.Abs(sum) / float64(n); m > bestMagnitude {
+			best, bestMagnitude = k, m
+		}
+	}
+	return best, bestMagnitude
+}
+
+func TestSimToneBin(t *testing.T) {
+	const n = 512
+	for _, offset := range []float64{128e3, -256e3} {
+		var d = openSim(t, 100e6, SimSignal{Type: SimTone, Frequency: 100e6 + offset, Amplitude: 0.5})
+		if err := d.SetSampleRate(1.024e6, 4); err != nil {
+			d.Close()
+			t.Fatal(err)
+		}
+
+		var expected = int(math.Round(offset/1.024e6*n+n)) % n
+		bin, magnitude := peakBin(captureSim(t, d, n))
+		d.Close()
+		if bin != expected {
+			t.Errorf("tone at %+.0f Hz: expected bin %d, got %d", offset, expected, bin)
+		}
+		if math.Abs(magnitude-0.5) > 0.01 {
+			t.Errorf("tone at %+.0f Hz: expected magnitude 0.5 at maximum gain, got %f", offset, magnitude)
+		}
+	}
+}
+
+func TestSimSampleRate(t *testing.T) {
+	const n = 512
+	var d = openSim(t, 100e6, SimSignal{Type: SimTone, Frequency: 100.128e6, Amplitude: 0.5})
+	defer d.Close()
+	if err := d.SetSampleRate(2.048e6, 8); err != nil {
+		t.Fatal(err)
+	}
+
+	host, rf, err := d.GetSampleRate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if host != 2.048e6 || rf != 2.048e6*8 {
+		t.Errorf("expected host 2048000 and rf 16384000 sps, got %f and %f", host, rf)
+	}
+
+	// 128 kHz at 2.048 Msps is bin 32 of 512
+	if bin, _ := peakBin(captureSim(t, d, n)); bin != 32 {
+		t.Errorf("expected bin 32, got %d", bin)
+	}
+
+	// Tones outside the sample rate are not received
+	if err := d.SetSampleRate(200e3, 4); err != nil {
+		t.Fatal(err)
+	}
+	if _, magnitude := peakBin(captureSim(t, d, n)); magnitude > 0.01 {
+		t.Errorf("expected no signal outside the sample rate, got magnitude %f", magnitude)
+	}
+}
+
+func TestSimGain(t *testing.T) {
+	const n = 256
+	var d = openSim(t, 100e6, SimSignal{Type: SimTone, Frequency: 100.125e6, Amplitude: 0.5})
+	defer d.Close()
+	if err := d.RXChannels[0].SetGainDB(simRXMaxGain - 20); err != nil {
+		t.Fatal(err)
+	}
+
+	// Amplitude is relative to the maximum gain, so 20 dB less is a tenth of it
+	if _, magnitude := peakBin(captureSim(t, d, n)); math.Abs(magnitude-0.05) > 0.002 {
+		t.Errorf("expected magnitude 0.05 at 20 dB below maximum gain, got %f", magnitude)
+	}
+
+	if err := d.RXChannels[0].SetGainNormalized(1); err != nil {
+		t.Fatal(err)
+	}
+	if gain, _ := d.RXChannels[0].GetGainDB(); gain != simRXMaxGain {
+		t.Errorf("expected normalized gain 1 to be %d dB, got %d", simRXMaxGain, gain)
+	}
+	if err := d.RXChannels[0].SetGainDB(simRXMaxGain + 1); err == nil {
+		t.Errorf("expected error setting gain above %d dB", simRXMaxGain)
+	}
+}
+
+func TestSimLPF(t *testing.T) {
+	const n = 256
+	var d = openSim(t, 100e6, SimSignal{Type: SimTone, Frequency: 102e6, Amplitude: 0.5})
+	defer d.Close()
+	if err := d.SetSampleRate(8e6, 2); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, magnitude := peakBin(captureSim(t, d, n)); magnitude < 0.4 {
+		t.Errorf("expected the tone with the LPF disabled, got magnitude %f", magnitude)
+	}
+
+	// A 2 MHz LPF passes 1 MHz at each side of the center frequency
+	var ch = d.RXChannels[0]
+	if err := ch.SetLPF(2e6); err != nil {
+		t.Fatal(err)
+	}
+	if err := ch.EnableLPF(); err != nil {
+		t.Fatal(err)
+	}
+	if _, magnitude := peakBin(captureSim(t, d, n)); magnitude > 0.01 {
+		t.Errorf("expected the tone to be filtered by the LPF, got magnitude %f", magnitude)
+	}
+
+	if err := ch.SetLPF(simRXLPFRange.Min / 2); err == nil {
+		t.Errorf("expected error setting LPF below %f", simRXLPFRange.Min)
+	}
+}
+
+func TestSimExternalReference(t *testing.T) {
+	var d = openSim(t, 100e6)
+	defer d.Close()
+
+	if err := d.SetExternalReference(10e6); err != nil {
+		t.Fatal(err)
+	}
+	if reference, err := d.GetClockFrequency(ClockReference); err != nil || reference != 10e6 {
+		t.Errorf("expected the external reference of 10 MHz as reference clock, got %f (%v)", reference, err)
+	}
+
+	if err := d.UseInternalReference(); err != nil {
+		t.Fatal(err)
+	}
+	if reference, err := d.GetClockFrequency(ClockReference); err != nil || reference != simReferenceClock {
+		t.Errorf("expected the internal reference clock %f, got %f (%v)", simReferenceClock, reference, err)
+	}
+}
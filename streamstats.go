@@ -0,0 +1,76 @@
+package limedrv
+
+import (
+	"sync/atomic"
+)
+
+// StreamStats is the health of the stream of a enabled channel.
+// Event counters are accumulated since the channel was enabled.
+type StreamStats struct {
+	// Channel is the channel number of the stream
+	Channel int
+	// IsRX is the direction of the stream
+	IsRX bool
+	// Active tells if the stream is running
+	Active bool
+	// FifoFilledCount is the number of samples in the stream FIFO
+	FifoFilledCount int
+	// FifoSize is the size of the stream FIFO in samples
+	FifoSize int
+	// Underruns is the number of FIFO underruns (TX Only)
+	Underruns uint64
+	// Overruns is the number of FIFO overruns (RX Only). Each overrun means samples were lost.
+	Overruns uint64
+	// DroppedPackets is the number of packets dropped by the hardware
+	DroppedPackets uint64
+	// Errors is the number of failed reads or writes in the stream
+	Errors uint64
+	// DroppedBlocks is the number of sample blocks dropped because the consumer of LMSChannel.Samples was too slow
+	DroppedBlocks uint64
+	// SampleRate is the measured sample rate of the stream in samples per second
+	SampleRate float64
+	// LinkRate is the measured data rate of the link with the device in bytes per second
+	LinkRate float64
+	// Timestamp is the current hardware sample counter
+	Timestamp uint64
+}
+
+// readStreamStats reads the stream status from the backend and accumulates it in the channel stats
+func (c *LMSChannel) readStreamStats() (StreamStats, error) {
+	c.statsMtx.Lock()
+	defer c.statsMtx.Unlock()
+
+	if c.stream == 0 {
+		return StreamStats{}, c.parent.channelError("get stream status", c.parentIndex, c.IsRX, nil, ErrStreamFailure)
+	}
+
+	status, err := c.parent.backend.GetStreamStatus(c.stream)
+	if err != nil {
+		return StreamStats{}, c.parent.channelError("get stream status", c.parentIndex, c.IsRX, err, ErrStreamFailure)
+	}
+
+	c.stats.Channel = c.parentIndex
+	c.stats.IsRX = c.IsRX
+	c.stats.Active = status.Active
+	c.stats.FifoFilledCount = status.FifoFilledCount
+	c.stats.FifoSize = status.FifoSize
+	c.stats.Underruns += uint64(status.Underrun)
+	c.stats.Overruns += uint64(status.Overrun)
+	c.stats.DroppedPackets += uint64(status.DroppedPackets)
+	c.stats.Errors = atomic.LoadUint64(&c.streamErrors)
+	c.stats.DroppedBlocks = atomic.LoadUint64(&c.droppedBlocks)
+	c.stats.SampleRate = status.SampleRate
+	c.stats.LinkRate = status.LinkRate
+	c.stats.Timestamp = status.Timestamp
+
+	return c.stats, nil
+}
+
+// resetStreamStats clears the accumulated stats. Used when a new stream is set up in the channel.
+func (c *LMSChannel) resetStreamStats() {
+	c.statsMtx.Lock()
+	defer c.statsMtx.Unlock()
+	c.stats = StreamStats{}
+	atomic.StoreUint64(&c.streamErrors, 0)
+	atomic.StoreUint64(&c.droppedBlocks, 0)
+}
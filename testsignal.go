@@ -0,0 +1,77 @@
+package limedrv
+
+import (
+	"fmt"
+	"runtime"
+)
+
+// TestSignal is a test signal generated internally by the LMS chip, replacing the received samples.
+// It is a known reference to validate the RX processing chain without an external signal generator.
+type TestSignal int
+
+const (
+	// TestSignalNone disables the test signal and returns to normal operation
+	TestSignalNone TestSignal = iota
+	// TestSignalNCODiv8 is a half scale tone from the test NCO at the sample rate divided by 8
+	TestSignalNCODiv8
+	// TestSignalNCODiv4 is a half scale tone from the test NCO at the sample rate divided by 4
+	TestSignalNCODiv4
+	// TestSignalNCODiv8Full is a full scale tone from the test NCO at the sample rate divided by 8
+	TestSignalNCODiv8Full
+	// TestSignalNCODiv4Full is a full scale tone from the test NCO at the sample rate divided by 4
+	TestSignalNCODiv4Full
+	// TestSignalDC is a constant DC signal with the I and Q values passed to SetTestSignal
+	TestSignalDC
+)
+
+var testSignalNames = map[TestSignal]string{
+	TestSignalNone:        "None",
+	TestSignalNCODiv8:     "NCODiv8",
+	TestSignalNCODiv4:     "NCODiv4",
+	TestSignalNCODiv8Full: "NCODiv8Full",
+	TestSignalNCODiv4Full: "NCODiv4Full",
+	TestSignalDC:          "DC",
+}
+
+// String returns the name of the test signal
+func (t TestSignal) String() string {
+	if name, ok := testSignalNames[t]; ok {
+		return name
+	}
+	return fmt.Sprintf("TestSignal(%d)", int(t))
+}
+
+// SetTestSignal replaces the samples of the specified channel by a test signal.
+// dcI and dcQ are the raw values of the DC signal, only used with TestSignalDC.
+func (d *LMSDevice) SetTestSignal(channelNumber int, isRX bool, signal TestSignal, dcI, dcQ int16) error {
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+	if _, ok := testSignalNames[signal]; !ok {
+		return d.channelError(fmt.Sprintf("set test signal %s", signal), channelNumber, isRX, nil, ErrOutOfRange)
+	}
+
+	if err := d.backend.SetTestSignal(d.dev, isRX, channelNumber, signal, dcI, dcQ); err != nil {
+		return d.channelError(fmt.Sprintf("set test signal %s", signal), channelNumber, isRX, err, nil)
+	}
+	return nil
+}
+
+// GetTestSignal returns the test signal currently set in the specified channel
+func (d *LMSDevice) GetTestSignal(channelNumber int, isRX bool) (signal TestSignal, err error) {
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+	if signal, err = d.backend.GetTestSignal(d.dev, isRX, channelNumber); err != nil {
+		return TestSignalNone, d.channelError("get test signal", channelNumber, isRX, err, nil)
+	}
+	return signal, nil
+}
+
+// SetTestSignal replaces the samples of the current channel by a test signal. dcI and dcQ are only used with TestSignalDC.
+func (c *LMSChannel) SetTestSignal(signal TestSignal, dcI, dcQ int16) error {
+	return c.parent.SetTestSignal(c.parentIndex, c.IsRX, signal, dcI, dcQ)
+}
+
+// TestSignal returns the test signal currently set in the current channel.
+func (c *LMSChannel) TestSignal() (TestSignal, error) {
+	return c.parent.GetTestSignal(c.parentIndex, c.IsRX)
+}
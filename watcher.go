@@ -0,0 +1,173 @@
+package limedrv
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// watchInterval is the interval between two device list checks of WatchDevices
+const watchInterval = time.Second
+
+// DeviceEventType tells if a device was added or removed
+type DeviceEventType int
+
+const (
+	// DeviceAdded is sent when a device shows up in the device list
+	DeviceAdded DeviceEventType = iota
+	// DeviceRemoved is sent when a device is no longer in the device list
+	DeviceRemoved
+)
+
+// String returns the name of the event type
+func (t DeviceEventType) String() string {
+	switch t {
+	case DeviceAdded:
+		return "Added"
+	case DeviceRemoved:
+		return "Removed"
+	}
+	return fmt.Sprintf("DeviceEventType(%d)", int(t))
+}
+
+// DeviceEvent is a change in the devices returned by GetDevices
+type DeviceEvent struct {
+	Type   DeviceEventType
+	Device DeviceInfo
+}
+
+var (
+	openDevicesMtx sync.Mutex
+	openDevices    = make(map[*LMSDevice]struct{})
+)
+
+// trackDevice adds a opened device to the devices checked by WatchDevices
+func trackDevice(d *LMSDevice) {
+	openDevicesMtx.Lock()
+	openDevices[d] = struct{}{}
+	openDevicesMtx.Unlock()
+}
+
+// untrackDevice removes a closed device from the devices checked by WatchDevices
+func untrackDevice(d *LMSDevice) {
+	openDevicesMtx.Lock()
+	delete(openDevices, d)
+	openDevicesMtx.Unlock()
+}
+
+// deviceKey identifies a device across device lists. Devices without serial are identified by their name and address.
+func deviceKey(d DeviceInfo) string {
+	if d.Serial != "" {
+		return d.Serial
+	}
+	return fmt.Sprintf("%s at %s %s", d.DeviceName, d.Media, d.Addr)
+}
+
+// WatchDevices checks the device list of the default and registered backends every second and sends a DeviceEvent
+// for each device plugged in or removed, until ctx is done. The devices present when it is called are sent as DeviceAdded.
+// It also checks if the boards of the opened devices are still connected, calling their removed callback (see SetRemovedCallback)
+// when they are not. If the device list of a backend cannot be read, the check is skipped, so no device is reported as removed.
+func WatchDevices(ctx context.Context) <-chan DeviceEvent {
+	var events = make(chan DeviceEvent, 16)
+
+	go func() {
+		defer close(events)
+		var ticker = time.NewTicker(watchInterval)
+		defer ticker.Stop()
+
+		var known = make(map[string]DeviceInfo)
+		for {
+			checkOpenDevices()
+
+			if devices, err := listAllDevices(); err == nil {
+				var changes []DeviceEvent
+				var current = make(map[string]DeviceInfo)
+				for _, d := range devices {
+					var key = deviceKey(d)
+					current[key] = d
+					if _, ok := known[key]; !ok {
+						changes = append(changes, DeviceEvent{Type: DeviceAdded, Device: d})
+					}
+				}
+				for key, d := range known {
+					if _, ok := current[key]; !ok {
+						changes = append(changes, DeviceEvent{Type: DeviceRemoved, Device: d})
+					}
+				}
+				known = current
+
+				for _, e := range changes {
+					select {
+					case events <- e:
+					case <-ctx.Done():
+						return
+					}
+				}
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+
+	return events
+}
+
+// listAllDevices is like GetDevices, but fails if any backend fails
+func listAllDevices() ([]DeviceInfo, error) {
+	var ret = make([]DeviceInfo, 0)
+	for _, backend := range allBackends() {
+		devices, err := GetDevicesFrom(backend)
+		if err != nil {
+			return nil, err
+		}
+		ret = append(ret, devices...)
+	}
+	return ret, nil
+}
+
+// checkOpenDevices notifies the opened devices whose board is no longer connected
+func checkOpenDevices() {
+	openDevicesMtx.Lock()
+	var devices = make([]*LMSDevice, 0, len(openDevices))
+	for d := range openDevices {
+		devices = append(devices, d)
+	}
+	openDevicesMtx.Unlock()
+
+	for _, d := range devices {
+		if d.IsConnected() || !atomic.CompareAndSwapInt32(&d.removed, 0, 1) {
+			continue
+		}
+		d.log(LogError, -1, false, nil, "device disconnected")
+		d.devMtx.Lock()
+		var cb = d.removedCallback
+		d.devMtx.Unlock()
+		if cb != nil {
+			cb()
+		}
+	}
+}
+
+// IsConnected returns true if the board of the device is still connected (LMS_IsOpen)
+func (d *LMSDevice) IsConnected() bool {
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+	d.devMtx.Lock()
+	defer d.devMtx.Unlock()
+	return d.dev != 0 && d.backend.IsOpen(d.dev)
+}
+
+// SetRemovedCallback sets a callback that is called once when WatchDevices finds that the board of the device was disconnected.
+// The device cannot be used after that and should be closed.
+func (d *LMSDevice) SetRemovedCallback(cb func()) {
+	d.devMtx.Lock()
+	defer d.devMtx.Unlock()
+	d.removedCallback = cb
+}
@@ -0,0 +1,94 @@
+package limedrv
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// waitDeviceEvent returns the next event of WatchDevices for the device with the serial
+func waitDeviceEvent(t *testing.T, events <-chan DeviceEvent, serial string) DeviceEvent {
+	t.Helper()
+	var timeout = time.After(5 * watchInterval)
+	for {
+		select {
+		case e, ok := <-events:
+			if !ok {
+				t.Fatal("the event channel was closed")
+			}
+			if e.Device.Serial == serial {
+				return e
+			}
+		case <-timeout:
+			t.Fatalf("no device event for serial %s", serial)
+		}
+	}
+}
+
+func TestWatchDevices(t *testing.T) {
+	// The registered backends are global, restore them for the other tests
+	defer func(backends []Backend) { registeredBackends = backends }(registeredBackends)
+	var sim = NewSimBackend()
+	RegisterBackend(sim)
+
+	list, err := GetDevicesFrom(sim)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var serial = list[0].Serial
+	d, err := Open(list[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer d.Close()
+
+	var removedCalls int32
+	var removed = make(chan struct{}, 1)
+	d.SetRemovedCallback(func() {
+		atomic.AddInt32(&removedCalls, 1)
+		select {
+		case removed <- struct{}{}:
+		default:
+		}
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var events = WatchDevices(ctx)
+	defer func() {
+		cancel()
+		for range events {
+		}
+	}()
+
+	if e := waitDeviceEvent(t, events, serial); e.Type != DeviceAdded {
+		t.Fatalf("expected the simulated device to be added, got %s", e.Type)
+	}
+	if !d.IsConnected() {
+		t.Errorf("expected the device to be connected")
+	}
+
+	sim.SetPlugged(false)
+	if e := waitDeviceEvent(t, events, serial); e.Type != DeviceRemoved {
+		t.Fatalf("expected the simulated device to be removed, got %s", e.Type)
+	}
+	select {
+	case <-removed:
+	case <-time.After(5 * watchInterval):
+		t.Fatal("the removed callback was not called")
+	}
+	if d.IsConnected() {
+		t.Errorf("expected the device to be disconnected")
+	}
+
+	// Let WatchDevices check the unplugged device again
+	time.Sleep(watchInterval + watchInterval/2)
+
+	sim.SetPlugged(true)
+	if e := waitDeviceEvent(t, events, serial); e.Type != DeviceAdded || e.Device.Serial != serial {
+		t.Fatalf("expected the simulated device to be added again, got %s of %s", e.Type, e.Device.Serial)
+	}
+	if calls := atomic.LoadInt32(&removedCalls); calls != 1 {
+		t.Errorf("expected the removed callback to be called once, got %d calls", calls)
+	}
+}
@@ -0,0 +1,60 @@
+package limedrv
+
+import (
+	"fmt"
+	"runtime"
+)
+
+// waveformMaxSamples is the maximum number of samples per channel accepted by UploadWaveform.
+// LimeSuite does not report the size of the FPGA waveform memory, and this limit was not verified against the gateware
+// of each board: it only rejects waveforms that are clearly too large before sending them.
+const waveformMaxSamples = 1 << 20
+
+// UploadWaveform uploads a waveform to the FPGA memory of the device, so it can be played in loop by the TX channels
+// without streaming from the host. channels has the samples of TX channel 0, 1 and so on, all with the same length,
+// in the [-1, 1] full scale range. format is the IQ format used to store the samples in the FPGA (FormatInt12, FormatInt16
+// or FormatFloat32), and samples are converted to it the same way as the TX streams. Use EnableWaveformPlayback to play it.
+func (d *LMSDevice) UploadWaveform(channels [][]complex64, format int) error {
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+	if len(channels) == 0 || len(channels) > len(d.TXChannels) {
+		return d.deviceError(fmt.Sprintf("upload waveform with %d channels", len(channels)), nil, ErrInvalidChannel)
+	}
+
+	if format != FormatFloat32 && format != FormatInt16 && format != FormatInt12 {
+		return d.deviceError(fmt.Sprintf("upload waveform with format %d", format), nil, ErrOutOfRange)
+	}
+
+	var sampleCount = len(channels[0])
+	if sampleCount == 0 || sampleCount > waveformMaxSamples {
+		return d.deviceError(fmt.Sprintf("upload waveform with %d samples (maximum is %d)", sampleCount, waveformMaxSamples), nil, ErrOutOfRange)
+	}
+
+	var buffers = make([][]byte, len(channels))
+	for i, samples := range channels {
+		if len(samples) != sampleCount {
+			return d.channelError(fmt.Sprintf("upload waveform with %d samples (channel 0 has %d)", len(samples), sampleCount), i, false, nil, ErrOutOfRange)
+		}
+		buffers[i] = make([]byte, sampleCount*2*iqFormatSampleSize(format))
+		encodeSamples(buffers[i], samples, format)
+	}
+
+	if err := d.backend.UploadWFM(d.dev, buffers, sampleCount, format); err != nil {
+		return d.deviceError(fmt.Sprintf("upload waveform with %d samples", sampleCount), err, nil)
+	}
+	return nil
+}
+
+// EnableWaveformPlayback starts or stops playing the waveform uploaded by UploadWaveform in loop in the specified TX channel.
+func (d *LMSDevice) EnableWaveformPlayback(channelNumber int, enabled bool) error {
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+	if _, err := d.getChannel(channelNumber, false); err != nil {
+		return err
+	}
+
+	if err := d.backend.EnableTxWFM(d.dev, channelNumber, enabled); err != nil {
+		return d.channelError(fmt.Sprintf("set waveform playback to %t", enabled), channelNumber, false, err, nil)
+	}
+	return nil
+}
@@ -0,0 +1,71 @@
+package limedrv
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+func TestUploadWaveformChecks(t *testing.T) {
+	var d = openSim(t, 100e6)
+	defer d.Close()
+	var samples = make([]complex64, 1024)
+
+	var tests = []struct {
+		name     string
+		channels [][]complex64
+		format   int
+		err      error
+	}{
+		{"no channels", nil, FormatInt16, ErrInvalidChannel},
+		{"more channels than the device", [][]complex64{samples, samples, samples}, FormatInt16, ErrInvalidChannel},
+		{"unknown format", [][]complex64{samples}, 42, ErrOutOfRange},
+		{"empty", [][]complex64{{}}, FormatInt16, ErrOutOfRange},
+		{"too long", [][]complex64{make([]complex64, waveformMaxSamples+1)}, FormatInt16, ErrOutOfRange},
+		{"different lengths", [][]complex64{samples, samples[:512]}, FormatInt16, ErrOutOfRange},
+	}
+
+	for _, tt := range tests {
+		if err := d.UploadWaveform(tt.channels, tt.format); !errors.Is(err, tt.err) {
+			t.Errorf("%s: expected %v, got %v", tt.name, tt.err, err)
+		}
+	}
+
+	if err := d.EnableWaveformPlayback(0, true); err == nil {
+		t.Errorf("expected error playing before uploading a waveform")
+	}
+}
+
+func TestUploadWaveform(t *testing.T) {
+	var d = openSim(t, 100e6)
+	defer d.Close()
+
+	var channels = [][]complex64{{0.5, -0.5i, 0.25 + 0.25i}, {1, 0, -1}}
+	for _, format := range []int{FormatInt12, FormatInt16, FormatFloat32} {
+		if err := d.UploadWaveform(channels, format); err != nil {
+			t.Fatal(err)
+		}
+
+		var s = d.backend.(*SimBackend)
+		s.mtx.Lock()
+		var uploaded = s.devices[d.dev].waveform
+		s.mtx.Unlock()
+
+		var tolerance = 1 / IQFormatFullScale(format)
+		for c, samples := range channels {
+			for i, want := range samples {
+				var got = uploaded[c][i]
+				if math.Abs(float64(real(got)-real(want))) > tolerance || math.Abs(float64(imag(got)-imag(want))) > tolerance {
+					t.Errorf("format %d channel %d sample %d: expected %v, got %v", format, c, i, want, got)
+				}
+			}
+		}
+	}
+
+	if err := d.EnableWaveformPlayback(1, true); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.EnableWaveformPlayback(2, true); !errors.Is(err, ErrInvalidChannel) {
+		t.Errorf("expected ErrInvalidChannel playing in TX channel 2, got %v", err)
+	}
+}